@@ -0,0 +1,436 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/craigatron/football-gobot/config"
+	"github.com/craigatron/football-gobot/store"
+)
+
+const activityNotifyInterval = time.Minute
+
+// activityEmbed formats a transaction for posting to a league's update channels.
+func activityEmbed(league config.League, teams map[int64]config.Team, activity config.Activity) *discordgo.MessageEmbed {
+	lines := make([]string, 0, len(activity.Actions))
+	for _, action := range activity.Actions {
+		lines = append(lines, config.FormatAction(league, teams, action))
+	}
+	return &discordgo.MessageEmbed{
+		Title:       fmt.Sprintf("%s transaction", league.Config().Name),
+		Description: strings.Join(lines, "\n"),
+		Timestamp:   time.UnixMilli(activity.Timestamp).Format(time.RFC3339),
+	}
+}
+
+// notifyActivity periodically posts new activity to each league's
+// bot_update_channels.  The newest posted activity is recorded in the store so
+// nothing is posted twice across restarts.
+func notifyActivity(s *discordgo.Session) {
+	for range time.Tick(activityNotifyInterval) {
+		for _, league := range currentState().leagues() {
+			if len(league.Config().BotUpdateChannels) == 0 {
+				continue
+			}
+			if err := notifyLeagueActivity(s, league); err != nil {
+				log.Printf("error notifying activity for %s league %s: %s", league.Type(), league.ID(), err)
+			}
+		}
+	}
+}
+
+func notifyLeagueActivity(s *discordgo.Session, league config.League) error {
+	ctx := context.Background()
+	leagueYear, err := db.LeagueYear(ctx, league)
+	if err != nil {
+		return err
+	}
+	if leagueYear.ActivityNotified == 0 {
+		// first run for this league, so don't post everything that's
+		// happened so far
+		log.Printf("starting activity notifications for %s league %s", league.Type(), league.ID())
+		return db.UpdateLeagueYear(ctx, league, store.LeagueYear{ActivityNotified: time.Now().UnixMilli()})
+	}
+
+	activity, err := db.ActivitySince(ctx, league, leagueYear.ActivityNotified)
+	if err != nil {
+		return err
+	}
+
+	teams := league.Teams()
+	for _, a := range activity {
+		embed := activityEmbed(league, teams, a)
+		for _, c := range league.Config().BotUpdateChannels {
+			if _, err := s.ChannelMessageSendEmbed(c, embed); err != nil {
+				log.Printf("error posting activity %s to channel %s: %s", a.ID, c, err)
+			}
+		}
+		if err := db.UpdateLeagueYear(ctx, league, store.LeagueYear{ActivityNotified: a.Timestamp}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// activityPageSize is how many transactions /activity shows per page.
+const activityPageSize = 10
+
+// activityButtonPrefix starts the custom IDs of /activity's page buttons.
+const activityButtonPrefix = "activity"
+
+// maxCustomIDLength is the longest custom ID Discord accepts on a component.
+const maxCustomIDLength = 100
+
+// activityActionTypes are the action filters /activity accepts, and whether
+// an action is one of them.  ESPN and Sleeper name actions the same way apart
+// from waivers, so they're matched loosely.
+var activityActionTypes = map[string]func(action string) bool{
+	"add": func(action string) bool {
+		return strings.Contains(action, "ADD") && !strings.Contains(action, "WAIVER")
+	},
+	"drop": func(action string) bool {
+		return strings.Contains(action, "DROP")
+	},
+	"trade": func(action string) bool {
+		return strings.Contains(action, "TRADE")
+	},
+	"waiver": func(action string) bool {
+		return strings.Contains(action, "WAIVER")
+	},
+}
+
+// activityQuery is a page of filtered activity.  It's encoded into the custom
+// IDs of the page buttons, so paging keeps working across restarts.
+type activityQuery struct {
+	leagueKey string
+	teamID    int64
+	// player matches player names containing it, ignoring case.
+	player string
+	action string
+	// since and until bound the activity's timestamps in Unix milliseconds,
+	// inclusive.  until is always set, so new activity doesn't shift pages.
+	since int64
+	until int64
+	// cursor is the timestamp the page continues from, or 0 for the first
+	// page.  Pages of newer activity start just after it, and pages of older
+	// activity just before.
+	cursor int64
+	newer  bool
+}
+
+// customID encodes the query for a button that pages to the activity newer
+// or older than cursor.
+func (q activityQuery) customID(cursor int64, newer bool) string {
+	direction := "o"
+	if newer {
+		direction = "n"
+	}
+	player := []rune(strings.ReplaceAll(q.player, "|", ""))
+	for {
+		id := strings.Join([]string{
+			activityButtonPrefix,
+			q.leagueKey,
+			strconv.FormatInt(q.teamID, 36),
+			string(player),
+			q.action,
+			strconv.FormatInt(q.since, 36),
+			strconv.FormatInt(q.until, 36),
+			direction + strconv.FormatInt(cursor, 36),
+		}, "|")
+		if len(id) <= maxCustomIDLength || len(player) == 0 {
+			return id
+		}
+		player = player[:len(player)-1]
+	}
+}
+
+func parseActivityQuery(customID string) (activityQuery, error) {
+	parts := strings.Split(customID, "|")
+	if len(parts) != 8 || parts[0] != activityButtonPrefix {
+		return activityQuery{}, fmt.Errorf("malformed activity custom ID %q", customID)
+	}
+	q := activityQuery{leagueKey: parts[1], player: parts[3], action: parts[4]}
+	if _, ok := activityActionTypes[q.action]; q.action != "" && !ok {
+		return q, fmt.Errorf("unknown activity action %q", q.action)
+	}
+	var err error
+	if q.teamID, err = strconv.ParseInt(parts[2], 36, 64); err != nil {
+		return q, err
+	}
+	if q.since, err = strconv.ParseInt(parts[5], 36, 64); err != nil {
+		return q, err
+	}
+	if q.until, err = strconv.ParseInt(parts[6], 36, 64); err != nil {
+		return q, err
+	}
+	cursor := parts[7]
+	switch {
+	case strings.HasPrefix(cursor, "n"):
+		q.newer = true
+	case !strings.HasPrefix(cursor, "o"):
+		return q, fmt.Errorf("malformed activity cursor %q", cursor)
+	}
+	if q.cursor, err = strconv.ParseInt(cursor[1:], 36, 64); err != nil {
+		return q, err
+	}
+	return q, nil
+}
+
+// matches reports whether any one action in the activity matches every
+// filter but the dates, which the store applies.
+func (q activityQuery) matches(league config.League, a config.Activity) bool {
+	player := strings.ToLower(q.player)
+	for _, action := range a.Actions {
+		if q.teamID != 0 && action.TeamID != q.teamID {
+			continue
+		}
+		if q.action != "" && !activityActionTypes[q.action](action.Action) {
+			continue
+		}
+		if player != "" && !strings.Contains(strings.ToLower(playerName(league, action.PlayerID)), player) {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// relativeTime describes how long before now t was, e.g. "3 hours ago".
+func relativeTime(t time.Time, now time.Time) string {
+	plural := func(n int, unit string) string {
+		if n == 1 {
+			return fmt.Sprintf("1 %s ago", unit)
+		}
+		return fmt.Sprintf("%d %ss ago", n, unit)
+	}
+	d := now.Sub(t)
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return plural(int(d/time.Minute), "minute")
+	case d < 24*time.Hour:
+		return plural(int(d/time.Hour), "hour")
+	case d < 48*time.Hour:
+		return "yesterday"
+	}
+	return plural(int(d/(24*time.Hour)), "day")
+}
+
+// activityPage shows a page of the query's activity, newest first, with
+// buttons to page to newer and older activity.
+func activityPage(ctx context.Context, league config.League, q activityQuery) (*discordgo.InteractionResponseData, error) {
+	loc, err := league.Config().Location()
+	if err != nil {
+		return nil, err
+	}
+	match := func(a config.Activity) bool {
+		return q.matches(league, a)
+	}
+	// anyMatch is whether any activity between since and until matches,
+	// which decides whether there's another page that way
+	anyMatch := func(since int64, until int64) (bool, error) {
+		if since > until {
+			return false, nil
+		}
+		found, err := db.QueryActivity(ctx, league, store.ActivityQuery{Since: since, Until: until, Match: match, Limit: 1})
+		return len(found) > 0, err
+	}
+
+	pageQuery := store.ActivityQuery{Since: q.since, Until: q.until, Match: match, Limit: activityPageSize}
+	switch {
+	case q.cursor == 0:
+	case q.newer:
+		pageQuery.Since = q.cursor + 1
+		pageQuery.OldestFirst = true
+	default:
+		pageQuery.Until = q.cursor - 1
+	}
+	var page []config.Activity
+	if pageQuery.Since <= pageQuery.Until {
+		if page, err = db.QueryActivity(ctx, league, pageQuery); err != nil {
+			return nil, err
+		}
+	}
+	if len(page) == 0 {
+		return &discordgo.InteractionResponseData{Content: "no activity matches"}, nil
+	}
+	if pageQuery.OldestFirst {
+		for i, j := 0, len(page)-1; i < j; i, j = i+1, j-1 {
+			page[i], page[j] = page[j], page[i]
+		}
+	}
+	newest, oldest := page[0].Timestamp, page[len(page)-1].Timestamp
+
+	// the page this one was reached from is always there to go back to, so
+	// only look for more in the direction being paged
+	hasNewer := q.cursor != 0 && !q.newer
+	hasOlder := q.newer
+	if q.newer {
+		if hasNewer, err = anyMatch(newest+1, q.until); err != nil {
+			return nil, err
+		}
+	} else if hasOlder, err = anyMatch(q.since, oldest-1); err != nil {
+		return nil, err
+	}
+
+	teams := league.Teams()
+	now := time.Now()
+	fields := make([]*discordgo.MessageEmbedField, 0, len(page))
+	for _, a := range page {
+		lines := make([]string, 0, len(a.Actions))
+		for _, action := range a.Actions {
+			lines = append(lines, config.FormatAction(league, teams, action))
+		}
+		t := time.UnixMilli(a.Timestamp).In(loc)
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:  fmt.Sprintf("%s (%s)", t.Format("Mon Jan 2, 3:04 PM MST"), relativeTime(t, now)),
+			Value: strings.Join(lines, "\n"),
+		})
+	}
+
+	return &discordgo.InteractionResponseData{
+		Embeds: []*discordgo.MessageEmbed{
+			{
+				Title:  fmt.Sprintf("%s activity", league.Config().Name),
+				Fields: fields,
+			},
+		},
+		Components: []discordgo.MessageComponent{
+			discordgo.ActionsRow{
+				Components: []discordgo.MessageComponent{
+					discordgo.Button{
+						Label:    "Newer",
+						Style:    discordgo.SecondaryButton,
+						CustomID: q.customID(newest, true),
+						Disabled: !hasNewer,
+					},
+					discordgo.Button{
+						Label:    "Older",
+						Style:    discordgo.SecondaryButton,
+						CustomID: q.customID(oldest, false),
+						Disabled: !hasOlder,
+					},
+				},
+			},
+		},
+	}, nil
+}
+
+// parseActivityDate parses a YYYY-MM-DD date in the league's timezone.
+func parseActivityDate(value string, loc *time.Location) (time.Time, error) {
+	return time.ParseInLocation("2006-01-02", value, loc)
+}
+
+func handleActivityCommand(s *discordgo.Session, i *discordgo.InteractionCreate, league config.League, channel *discordgo.Channel) {
+	loc, err := league.Config().Location()
+	if err != nil {
+		respondError(s, i, "could not get the league's timezone", err)
+		return
+	}
+	q := activityQuery{leagueKey: config.LeagueKey(league), until: time.Now().UnixMilli()}
+	for _, o := range i.ApplicationCommandData().Options {
+		switch o.Name {
+		case "team":
+			q.teamID, err = strconv.ParseInt(o.StringValue(), 10, 64)
+			if _, ok := league.Teams()[q.teamID]; err != nil || !ok {
+				respondPrivately(s, i, "pick a team from the list")
+				return
+			}
+		case "player":
+			q.player = o.StringValue()
+		case "action":
+			q.action = o.StringValue()
+		case "since", "until":
+			t, err := parseActivityDate(o.StringValue(), loc)
+			if err != nil {
+				respondPrivately(s, i, fmt.Sprintf("%s must be a date like 2023-10-31", o.Name))
+				return
+			}
+			if o.Name == "since" {
+				q.since = t.UnixMilli()
+			} else if end := t.AddDate(0, 0, 1).UnixMilli() - 1; end < q.until {
+				q.until = end
+			}
+		}
+	}
+	if q.since > q.until {
+		respondPrivately(s, i, "since must be before until")
+		return
+	}
+
+	data, err := activityPage(context.Background(), league, q)
+	if err != nil {
+		respondError(s, i, "could not get activity for league", err)
+		return
+	}
+	respond(s, i, data)
+}
+
+// handleActivityButton shows the page of activity a button points to in
+// place of the current one.  The update is deferred first, since loading a
+// filtered page can take longer than Discord waits for a response.
+func handleActivityButton(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredMessageUpdate,
+	})
+	if err != nil {
+		log.Printf("error deferring activity page: %s", err)
+		return
+	}
+	reply := func(content string) {
+		_, err := s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		})
+		if err != nil {
+			log.Printf("error replying to activity button: %s", err)
+		}
+	}
+
+	q, err := parseActivityQuery(i.MessageComponentData().CustomID)
+	if err != nil {
+		log.Printf("error parsing activity button: %s", err)
+		reply("could not load that page, run /activity again")
+		return
+	}
+	league, ok := currentState().leaguesByKey[q.leagueKey]
+	if !ok {
+		reply("that league isn't set up anymore")
+		return
+	}
+	data, err := activityPage(context.Background(), league, q)
+	if err != nil {
+		log.Printf("error getting activity page: %s", err)
+		reply("could not get activity for league")
+		return
+	}
+	// empty rather than nil, so a page with nothing to show clears the old
+	// page's embed and buttons
+	embeds := append([]*discordgo.MessageEmbed{}, data.Embeds...)
+	components := append([]discordgo.MessageComponent{}, data.Components...)
+	_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content:    &data.Content,
+		Embeds:     &embeds,
+		Components: &components,
+	})
+	if err != nil {
+		log.Printf("error updating activity page: %s", err)
+	}
+}
+
+// componentHandler handles clicks on message components, like /activity's
+// page buttons.
+func componentHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if i.Type != discordgo.InteractionMessageComponent {
+		return
+	}
+	if strings.HasPrefix(i.MessageComponentData().CustomID, activityButtonPrefix+"|") {
+		handleActivityButton(s, i)
+	}
+}
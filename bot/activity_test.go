@@ -0,0 +1,256 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/craigatron/football-gobot/config"
+	"github.com/craigatron/football-gobot/config/configtest"
+	"github.com/craigatron/football-gobot/store"
+)
+
+// activityLeague is a league with two teams and players "0" to "22".
+func activityLeague() configtest.League {
+	players := make(map[string]config.Player)
+	for i := 0; i < 23; i++ {
+		id := fmt.Sprint(i)
+		players[id] = config.Player{ID: id, FullName: "Player " + id}
+	}
+	return configtest.League{
+		TeamsByID:   map[int64]config.Team{1: {ID: 1, Name: "One"}, 2: {ID: 2, Name: "Two"}},
+		PlayersByID: players,
+		Settings:    config.LeagueConfigJSON{Name: "Test League"},
+	}
+}
+
+func TestActivityQueryCustomID(t *testing.T) {
+	tests := []struct {
+		name   string
+		q      activityQuery
+		cursor int64
+		newer  bool
+	}{
+		{"first page", activityQuery{leagueKey: "leagues/sleeper-123", until: 1666000000000}, 1665000000000, false},
+		{"newer", activityQuery{leagueKey: "leagues/sleeper-123", until: 1666000000000}, 1665000000000, true},
+		{
+			name: "every filter",
+			q: activityQuery{
+				leagueKey: "leagues/espn-456",
+				teamID:    12,
+				player:    "Jamarr Chase",
+				action:    "waiver",
+				since:     1660000000000,
+				until:     1666000000000,
+			},
+			cursor: 1663000000000,
+			newer:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id := tt.q.customID(tt.cursor, tt.newer)
+			if len(id) > maxCustomIDLength {
+				t.Errorf("customID() = %q, longer than %d", id, maxCustomIDLength)
+			}
+			got, err := parseActivityQuery(id)
+			if err != nil {
+				t.Fatalf("parseActivityQuery(%q) error = %v", id, err)
+			}
+			want := tt.q
+			want.cursor = tt.cursor
+			want.newer = tt.newer
+			if got != want {
+				t.Errorf("parseActivityQuery(%q) = %+v, want %+v", id, got, want)
+			}
+		})
+	}
+}
+
+func TestActivityQueryCustomIDTruncatesPlayer(t *testing.T) {
+	q := activityQuery{
+		leagueKey: "leagues/sleeper-123456789012345678",
+		player:    strings.Repeat("ü", 40) + "|",
+		action:    "trade",
+		until:     1666000000000,
+	}
+	id := q.customID(1665000000000, false)
+	if len(id) > maxCustomIDLength {
+		t.Fatalf("customID() = %q, longer than %d", id, maxCustomIDLength)
+	}
+	got, err := parseActivityQuery(id)
+	if err != nil {
+		t.Fatalf("parseActivityQuery(%q) error = %v", id, err)
+	}
+	if got.player == "" || !strings.HasPrefix(q.player, got.player) {
+		t.Errorf("player = %q, want a prefix of %q", got.player, q.player)
+	}
+	if got.action != q.action || got.until != q.until || got.cursor != 1665000000000 {
+		t.Errorf("parseActivityQuery(%q) = %+v, want the rest of %+v kept", id, got, q)
+	}
+}
+
+func TestParseActivityQueryErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"activity|leagues/sleeper-1|0||||",
+		"other|leagues/sleeper-1|0|||0|1|o1",
+		"activity|leagues/sleeper-1|zz!|||0|1|o1",
+		"activity|leagues/sleeper-1|0||vibes|0|1|o1",
+		"activity|leagues/sleeper-1|0|||0|1|x1",
+		"activity|leagues/sleeper-1|0|||0|1|o",
+		// pages from before cursors were encoded
+		"activity|leagues/sleeper-1|0|||0|1|2",
+	}
+	for _, id := range tests {
+		if q, err := parseActivityQuery(id); err == nil {
+			t.Errorf("parseActivityQuery(%q) = %+v, want an error", id, q)
+		}
+	}
+}
+
+// activityButtons returns the Newer and Older buttons on a page.
+func activityButtons(t *testing.T, data *discordgo.InteractionResponseData) (discordgo.Button, discordgo.Button) {
+	t.Helper()
+	if len(data.Components) != 1 {
+		t.Fatalf("page has %d component rows, want 1", len(data.Components))
+	}
+	row := data.Components[0].(discordgo.ActionsRow)
+	return row.Components[0].(discordgo.Button), row.Components[1].(discordgo.Button)
+}
+
+func TestActivityPage(t *testing.T) {
+	ctx := context.Background()
+	league := activityLeague()
+	db = store.NewMemory()
+
+	// 23 transactions with ties at page boundaries, alternating teams
+	activity := make([]config.Activity, 0)
+	for i := 0; i < 23; i++ {
+		timestamp := int64(1000 + i*10)
+		if i == 10 || i == 13 {
+			timestamp -= 10
+		}
+		activity = append(activity, config.Activity{
+			ID:        fmt.Sprintf("%02d", i),
+			Timestamp: timestamp,
+			Actions:   []config.ActivityAction{{Action: config.ActionAdded, PlayerID: fmt.Sprint(i), TeamID: int64(1 + i%2)}},
+		})
+	}
+	if err := db.AddActivity(ctx, league, activity, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		q    activityQuery
+		want []config.Activity
+	}{
+		{"everything", activityQuery{until: 2000}, activity},
+		{"by team", activityQuery{until: 2000, teamID: 2}, everyOther(activity, 1)},
+		{"by date", activityQuery{since: 1050, until: 1100}, activity[5:11]},
+		{"by player", activityQuery{until: 2000, player: "player 1"}, []config.Activity{activity[1], activity[10], activity[11], activity[12], activity[13], activity[14], activity[15], activity[16], activity[17], activity[18], activity[19]}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.q.leagueKey = config.LeagueKey(league)
+			want := make([]string, 0, len(tt.want))
+			for i := len(tt.want) - 1; i >= 0; i-- {
+				want = append(want, tt.want[i].ID)
+			}
+
+			// page through to the oldest activity and back again
+			older := pageThrough(t, league, tt.q, false)
+			if !reflect.DeepEqual(older.ids, want) {
+				t.Errorf("paging older = %q, want %q", older.ids, want)
+			}
+			newer := pageThrough(t, league, older.last, true)
+			wantNewer := want[:len(want)-older.lastPageSize]
+			if !reflect.DeepEqual(newer.ids, wantNewer) {
+				t.Errorf("paging newer = %q, want %q", newer.ids, wantNewer)
+			}
+		})
+	}
+}
+
+func everyOther(activity []config.Activity, start int) []config.Activity {
+	filtered := make([]config.Activity, 0)
+	for i := start; i < len(activity); i += 2 {
+		filtered = append(filtered, activity[i])
+	}
+	return filtered
+}
+
+// pagedActivity is the activity seen paging in one direction, newest first.
+type pagedActivity struct {
+	ids []string
+	// last is the last page's query and lastPageSize how many it showed.
+	last         activityQuery
+	lastPageSize int
+}
+
+// pageThrough follows the Older or Newer buttons from q until they're
+// disabled, collecting the IDs of the activity shown after q's page.  Paging
+// older includes q's own page.
+func pageThrough(t *testing.T, league config.League, q activityQuery, newer bool) pagedActivity {
+	t.Helper()
+	seen := pagedActivity{ids: make([]string, 0)}
+	first := true
+	for pages := 0; pages < 10; pages++ {
+		data, err := activityPage(context.Background(), league, q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(data.Embeds) != 1 {
+			t.Fatalf("page %+v = %q, want an embed", q, data.Content)
+		}
+		ids := make([]string, 0)
+		for _, f := range data.Embeds[0].Fields {
+			ids = append(ids, activityIDForField(t, f))
+		}
+		if len(ids) > activityPageSize+1 {
+			t.Errorf("page %+v shows %d transactions, want at most a page and ties", q, len(ids))
+		}
+		if newer && !first {
+			seen.ids = append(ids, seen.ids...)
+		} else if !newer {
+			seen.ids = append(seen.ids, ids...)
+		}
+		seen.last = q
+		seen.lastPageSize = len(ids)
+		first = false
+
+		newerButton, olderButton := activityButtons(t, data)
+		next := olderButton
+		if newer {
+			next = newerButton
+		}
+		if next.Disabled {
+			return seen
+		}
+		if q, err = parseActivityQuery(next.CustomID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Fatalf("still paging from %+v after 10 pages", q)
+	return seen
+}
+
+// activityIDForField recovers the activity ID from a page's field, which
+// shows its one player.
+func activityIDForField(t *testing.T, f *discordgo.MessageEmbedField) string {
+	t.Helper()
+	i := strings.Index(f.Value, "Player ")
+	if i < 0 {
+		t.Fatalf("field %q doesn't name a player", f.Value)
+	}
+	var n int
+	if _, err := fmt.Sscanf(f.Value[i:], "Player %d", &n); err != nil {
+		t.Fatalf("field %q: %s", f.Value, err)
+	}
+	return fmt.Sprintf("%02d", n)
+}
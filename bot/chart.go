@@ -0,0 +1,311 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/craigatron/football-gobot/config"
+	"github.com/craigatron/football-gobot/store"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+	"golang.org/x/image/vector"
+)
+
+// The chart is laid out like update-scores' matchup.html.
+const (
+	chartWidth        = 960
+	chartHeight       = 500
+	chartMarginTop    = 60
+	chartMarginRight  = 20
+	chartMarginBottom = 30
+	chartMarginLeft   = 50
+	chartLineWidth    = 2
+	chartYTicks       = 6
+	chartXTicks       = 6
+)
+
+var (
+	chartTextColor = color.RGBA{0x33, 0x33, 0x33, 0xff}
+	chartGridColor = color.RGBA{0xe0, 0xe0, 0xe0, 0xff}
+	// steelblue and red, as in matchup.html
+	chartTeamColors = [2]color.RGBA{{0x46, 0x82, 0xb4, 0xff}, {0xff, 0x00, 0x00, 0xff}}
+)
+
+// chartPoint is a value at a point in time, in Unix milliseconds.
+type chartPoint struct {
+	Timestamp int64
+	Value     float64
+}
+
+// matchupChart renders a matchup's projections over the week as an
+// attachment, or returns nil if there aren't any to chart yet.
+func matchupChart(league config.League, teams map[int64]config.Team, matchup config.Matchup, projections []store.Projection) *discordgo.File {
+	projectionPoint := func(p store.Projection) (chartPoint, bool) {
+		return chartPoint{Timestamp: p.Timestamp, Value: p.Projection}, true
+	}
+	title := fmt.Sprintf("%s week %d: %s vs %s", league.Config().Name, matchup.Week, teams[matchup.HomeTeamID].Name, teams[matchup.AwayTeamID].Name)
+	return renderMatchupChart(fmt.Sprintf("matchup-%d.png", matchup.ID), title, league, teams, matchup, projections, projectionPoint, false)
+}
+
+// winProbabilityChart renders each team's chance of winning a matchup over
+// the week as an attachment, or returns nil if there aren't any to chart yet.
+func winProbabilityChart(league config.League, teams map[int64]config.Team, matchup config.Matchup, projections []store.Projection) *discordgo.File {
+	// projections saved before win probabilities were tracked have zero for
+	// both teams
+	tracked := make(map[int64]bool)
+	for _, p := range projections {
+		if p.MatchupID == matchup.ID && p.WinProbability > 0 {
+			tracked[p.Timestamp] = true
+		}
+	}
+	winProbabilityPoint := func(p store.Projection) (chartPoint, bool) {
+		return chartPoint{Timestamp: p.Timestamp, Value: p.WinProbability * 100}, tracked[p.Timestamp]
+	}
+	title := fmt.Sprintf("%s week %d win probability: %s vs %s", league.Config().Name, matchup.Week, teams[matchup.HomeTeamID].Name, teams[matchup.AwayTeamID].Name)
+	return renderMatchupChart(fmt.Sprintf("winprob-%d.png", matchup.ID), title, league, teams, matchup, projections, winProbabilityPoint, true)
+}
+
+// renderMatchupChart charts the points selected from each team's projections,
+// labelling times in the league's timezone.
+func renderMatchupChart(name string, title string, league config.League, teams map[int64]config.Team, matchup config.Matchup, projections []store.Projection, point func(store.Projection) (chartPoint, bool), percent bool) *discordgo.File {
+	series := [2][]chartPoint{}
+	for i, teamID := range [2]int64{matchup.HomeTeamID, matchup.AwayTeamID} {
+		series[i] = make([]chartPoint, 0)
+		for _, p := range teamProjections(projections, teamID) {
+			if cp, ok := point(p); ok {
+				series[i] = append(series[i], cp)
+			}
+		}
+	}
+	if len(series[0]) == 0 && len(series[1]) == 0 {
+		return nil
+	}
+
+	loc, err := league.Config().Location()
+	if err != nil {
+		log.Printf("error getting timezone for matchup %d chart: %s", matchup.ID, err)
+		return nil
+	}
+	buf := &bytes.Buffer{}
+	names := [2]string{teams[matchup.HomeTeamID].Name, teams[matchup.AwayTeamID].Name}
+	if err := writeChartPNG(buf, title, names, series, percent, loc); err != nil {
+		log.Printf("error rendering chart for matchup %d: %s", matchup.ID, err)
+		return nil
+	}
+	return &discordgo.File{
+		Name:        name,
+		ContentType: "image/png",
+		Reader:      buf,
+	}
+}
+
+// teamProjections returns a team's projections, oldest first.
+func teamProjections(projections []store.Projection, teamID int64) []store.Projection {
+	tp := make([]store.Projection, 0)
+	for _, p := range projections {
+		if p.TeamID == teamID {
+			tp = append(tp, p)
+		}
+	}
+	sort.SliceStable(tp, func(i, j int) bool {
+		return tp[i].Timestamp < tp[j].Timestamp
+	})
+	return tp
+}
+
+// writeChartPNG draws two teams' series over time as a PNG, with times
+// labelled in loc.  Percent charts always span 0 to 100.
+func writeChartPNG(w io.Writer, title string, names [2]string, series [2][]chartPoint, percent bool, loc *time.Location) error {
+	if len(series[0]) == 0 && len(series[1]) == 0 {
+		return fmt.Errorf("no points to chart")
+	}
+
+	minT, maxT := int64(math.MaxInt64), int64(math.MinInt64)
+	minY, maxY := math.Inf(1), math.Inf(-1)
+	for _, points := range series {
+		for _, p := range points {
+			if p.Timestamp < minT {
+				minT = p.Timestamp
+			}
+			if p.Timestamp > maxT {
+				maxT = p.Timestamp
+			}
+			minY = math.Min(minY, p.Value)
+			maxY = math.Max(maxY, p.Value)
+		}
+	}
+	if percent {
+		minY, maxY = 0, 100
+	}
+	if minT == maxT {
+		minT -= int64(time.Minute / time.Millisecond)
+		maxT += int64(time.Minute / time.Millisecond)
+	}
+	step := niceStep((maxY - minY) / chartYTicks)
+	minY = math.Floor(minY/step) * step
+	maxY = math.Ceil(maxY/step) * step
+	if minY == maxY {
+		maxY += step
+	}
+
+	plotW := float64(chartWidth - chartMarginLeft - chartMarginRight)
+	plotH := float64(chartHeight - chartMarginTop - chartMarginBottom)
+	x := func(t int64) float64 {
+		return chartMarginLeft + float64(t-minT)/float64(maxT-minT)*plotW
+	}
+	y := func(v float64) float64 {
+		return chartMarginTop + (maxY-v)/(maxY-minY)*plotH
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, chartWidth, chartHeight))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+
+	// y axis gridlines and labels
+	for v := minY; v <= maxY+step/2; v += step {
+		py := int(math.Round(y(v)))
+		draw.Draw(img, image.Rect(chartMarginLeft, py, chartWidth-chartMarginRight, py+1), image.NewUniform(chartGridColor), image.Point{}, draw.Src)
+		label := fmt.Sprintf("%g", v)
+		if percent {
+			label += "%"
+		}
+		drawText(img, label, chartMarginLeft-8-textWidth(label), py+4, chartTextColor)
+	}
+
+	// x axis and time labels
+	axisY := chartHeight - chartMarginBottom
+	draw.Draw(img, image.Rect(chartMarginLeft, axisY, chartWidth-chartMarginRight, axisY+1), image.NewUniform(chartTextColor), image.Point{}, draw.Src)
+	for i := 0; i <= chartXTicks; i++ {
+		t := minT + (maxT-minT)*int64(i)/chartXTicks
+		px := int(math.Round(x(t)))
+		draw.Draw(img, image.Rect(px, axisY, px+1, axisY+5), image.NewUniform(chartTextColor), image.Point{}, draw.Src)
+		label := time.UnixMilli(t).In(loc).Format("Mon 3:04PM")
+		lx := px - textWidth(label)/2
+		if lx+textWidth(label) > chartWidth {
+			lx = chartWidth - textWidth(label)
+		}
+		drawText(img, label, lx, axisY+18, chartTextColor)
+	}
+
+	for i, points := range series {
+		xy := make([][2]float64, 0, len(points))
+		for _, p := range points {
+			xy = append(xy, [2]float64{x(p.Timestamp), y(p.Value)})
+		}
+		drawLine(img, xy, chartTeamColors[i])
+	}
+
+	// title and a legend with each team's latest value
+	drawText(img, title, chartMarginLeft, 20, chartTextColor)
+	lx := chartMarginLeft
+	for i, name := range names {
+		label := name
+		if points := series[i]; len(points) > 0 {
+			if percent {
+				label = fmt.Sprintf("%s (%.0f%%)", name, points[len(points)-1].Value)
+			} else {
+				label = fmt.Sprintf("%s (%.2f)", name, points[len(points)-1].Value)
+			}
+		}
+		draw.Draw(img, image.Rect(lx, 33, lx+12, 45), image.NewUniform(chartTeamColors[i]), image.Point{}, draw.Src)
+		drawText(img, label, lx+18, 44, chartTextColor)
+		lx += 18 + textWidth(label) + 30
+	}
+
+	return png.Encode(w, img)
+}
+
+// niceStep rounds a tick step up to 1, 2 or 5 times a power of ten.
+func niceStep(raw float64) float64 {
+	if raw <= 0 {
+		return 1
+	}
+	mag := math.Pow(10, math.Floor(math.Log10(raw)))
+	for _, m := range []float64{1, 2, 5, 10} {
+		if raw <= m*mag {
+			return m * mag
+		}
+	}
+	return 10 * mag
+}
+
+func textWidth(s string) int {
+	return font.MeasureString(basicfont.Face7x13, s).Round()
+}
+
+// drawText draws s with its baseline starting at x, y.
+func drawText(img draw.Image, s string, x int, y int, c color.Color) {
+	d := &font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(c),
+		Face: basicfont.Face7x13,
+		Dot:  fixed.P(x, y),
+	}
+	d.DrawString(s)
+}
+
+// drawLine strokes an anti-aliased line through points, with a dot at each
+// point so joins are smooth and single points still show up.
+func drawLine(img draw.Image, points [][2]float64, c color.Color) {
+	b := img.Bounds()
+	r := vector.NewRasterizer(b.Dx(), b.Dy())
+	hw := float64(chartLineWidth) / 2
+	for i, p := range points {
+		// an octagon approximates a round join at this size
+		dot := make([][2]float64, 0, 8)
+		for k := 0; k < 8; k++ {
+			a := float64(k) * math.Pi / 4
+			dot = append(dot, [2]float64{p[0] + hw*math.Cos(a), p[1] + hw*math.Sin(a)})
+		}
+		addPolygon(r, dot)
+
+		if i == 0 {
+			continue
+		}
+		q := points[i-1]
+		dx, dy := p[0]-q[0], p[1]-q[1]
+		length := math.Hypot(dx, dy)
+		if length == 0 {
+			continue
+		}
+		nx, ny := -dy/length*hw, dx/length*hw
+		addPolygon(r, [][2]float64{
+			{q[0] + nx, q[1] + ny},
+			{p[0] + nx, p[1] + ny},
+			{p[0] - nx, p[1] - ny},
+			{q[0] - nx, q[1] - ny},
+		})
+	}
+	r.DrawOp = draw.Over
+	r.Draw(img, b, image.NewUniform(c), image.Point{})
+}
+
+// addPolygon adds a closed path to r, always wound the same way so that
+// overlapping polygons don't cancel each other out.
+func addPolygon(r *vector.Rasterizer, pts [][2]float64) {
+	area := 0.0
+	for i, p := range pts {
+		q := pts[(i+1)%len(pts)]
+		area += p[0]*q[1] - q[0]*p[1]
+	}
+	if area < 0 {
+		for i, j := 0, len(pts)-1; i < j; i, j = i+1, j-1 {
+			pts[i], pts[j] = pts[j], pts[i]
+		}
+	}
+	r.MoveTo(float32(pts[0][0]), float32(pts[0][1]))
+	for _, p := range pts[1:] {
+		r.LineTo(float32(p[0]), float32(p[1]))
+	}
+	r.ClosePath()
+}
@@ -0,0 +1,353 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/craigatron/football-gobot/config"
+)
+
+// commandHandlerFunc handles a slash command for the league it was used in,
+// which is nil for leagueless commands.
+type commandHandlerFunc func(s *discordgo.Session, i *discordgo.InteractionCreate, league config.League, channel *discordgo.Channel)
+
+// command is a slash command the bot registers with Discord and dispatches
+// interactions to.
+type command struct {
+	name        string
+	description string
+	options     []*discordgo.ApplicationCommandOption
+	// leagueless commands don't use a league, so they work anywhere and
+	// aren't passed one.
+	leagueless bool
+	// permissions are the permissions a member needs to see and use the
+	// command, or zero for everyone.  Server admins can override them.
+	permissions int64
+	handler     commandHandlerFunc
+	// autocomplete answers autocomplete for the command's options, if any of
+	// them set Autocomplete.
+	autocomplete func(s *discordgo.Session, i *discordgo.InteractionCreate, league config.League)
+}
+
+var minWeek = 1.0
+
+// commands are every slash command the bot has.  Adding one here registers it
+// at startup and dispatches it.
+var commands = []command{
+	{
+		name:        "bot-version",
+		description: "See what version of FOOTBALL GOBOT is active",
+		leagueless:  true,
+		handler: func(s *discordgo.Session, i *discordgo.InteractionCreate, league config.League, channel *discordgo.Channel) {
+			handleBotVersionCommand(s, i)
+		},
+	},
+	{
+		name:        "debug",
+		description: "Get debug info about GOBOT",
+		permissions: discordgo.PermissionManageServer,
+		handler:     handleDebugCommand,
+	},
+	{
+		name:        "activity",
+		description: "Show recent activity for this league",
+		options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:         discordgo.ApplicationCommandOptionString,
+				Name:         "team",
+				Description:  "Only show this team's activity",
+				Autocomplete: true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "player",
+				Description: "Only show activity for players whose name contains this",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "action",
+				Description: "Only show this kind of move",
+				Choices: []*discordgo.ApplicationCommandOptionChoice{
+					{Name: "add", Value: "add"},
+					{Name: "drop", Value: "drop"},
+					{Name: "trade", Value: "trade"},
+					{Name: "waiver", Value: "waiver"},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "since",
+				Description: "Only show activity on or after this date, e.g. 2023-10-31",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "until",
+				Description: "Only show activity on or before this date, e.g. 2023-10-31",
+			},
+		},
+		handler:      handleActivityCommand,
+		autocomplete: handleTeamAutocomplete,
+	},
+	{
+		name:        "charts",
+		description: "Get link to current projections charts",
+		handler:     handleChartsCommand,
+	},
+	{
+		name:        "standings",
+		description: "Show the standings for this league",
+		handler:     handleStandingsCommand,
+	},
+	{
+		name:        "scoreboard",
+		description: "Show scores and projections for every matchup",
+		options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionInteger,
+				Name:        "week",
+				Description: "Week to show, defaults to the current week",
+				MinValue:    &minWeek,
+				MaxValue:    18,
+			},
+		},
+		handler: handleScoreboardCommand,
+	},
+	{
+		name:        "matchup",
+		description: "Show a team's lineup against their opponent this week",
+		options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:         discordgo.ApplicationCommandOptionString,
+				Name:         "team",
+				Description:  "Team to show, defaults to your linked team",
+				Autocomplete: true,
+			},
+		},
+		handler:      handleMatchupCommand,
+		autocomplete: handleTeamAutocomplete,
+	},
+	{
+		name:        "winprob",
+		description: "Show how a team's chance of winning this week has swung",
+		options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:         discordgo.ApplicationCommandOptionString,
+				Name:         "team",
+				Description:  "Team to show, defaults to your linked team",
+				Autocomplete: true,
+			},
+		},
+		handler:      handleWinProbabilityCommand,
+		autocomplete: handleTeamAutocomplete,
+	},
+	{
+		name:        "recap",
+		description: "Show the recap of a finished week",
+		options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionInteger,
+				Name:        "week",
+				Description: "Week to recap, defaults to the last finished week",
+				MinValue:    &minWeek,
+				MaxValue:    18,
+			},
+		},
+		handler: handleRecapCommand,
+	},
+	{
+		name:        "powerrankings",
+		description: "Show the league's power rankings after a finished week",
+		options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionInteger,
+				Name:        "week",
+				Description: "Week to rank through, defaults to the last finished week",
+				MinValue:    &minWeek,
+				MaxValue:    18,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "formula",
+				Description: "Rank by a single formula instead of the league's",
+				Choices:     formulaChoices(),
+			},
+		},
+		handler: handlePowerRankingsCommand,
+	},
+	{
+		name:        "player",
+		description: "Look up an NFL player's status, scores and moves in this league",
+		options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:         discordgo.ApplicationCommandOptionString,
+				Name:         "name",
+				Description:  "Player to look up",
+				Required:     true,
+				Autocomplete: true,
+			},
+		},
+		handler:      handlePlayerCommand,
+		autocomplete: handlePlayerAutocomplete,
+	},
+	{
+		name:        "link",
+		description: "Link your Discord account to your team in this league",
+		options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:         discordgo.ApplicationCommandOptionString,
+				Name:         "team",
+				Description:  "Your team",
+				Required:     true,
+				Autocomplete: true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionUser,
+				Name:        "user",
+				Description: "User to link instead of yourself (admins only)",
+			},
+		},
+		handler:      handleLinkCommand,
+		autocomplete: handleTeamAutocomplete,
+	},
+	{
+		name:        "whoami",
+		description: "Show which team you're linked to in this league",
+		handler:     handleWhoamiCommand,
+	},
+	{
+		name:        "defaultleague",
+		description: "Set the league commands use in this server outside league categories",
+		options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:         discordgo.ApplicationCommandOptionString,
+				Name:         leagueOption,
+				Description:  "League to use by default",
+				Required:     true,
+				Autocomplete: true,
+			},
+		},
+		permissions: discordgo.PermissionManageServer,
+		handler:     handleDefaultLeagueCommand,
+	},
+}
+
+// commandsByName indexes commands for dispatch.
+var commandsByName = func() map[string]command {
+	byName := make(map[string]command)
+	for _, c := range commands {
+		byName[c.name] = c
+	}
+	return byName
+}()
+
+func formulaChoices() []*discordgo.ApplicationCommandOptionChoice {
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, 0)
+	for _, name := range config.RankingFormulaNames() {
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: name, Value: name})
+	}
+	return choices
+}
+
+// applicationCommand is the command as registered with Discord.  Commands
+// that use a league get a league option unless they declare their own.
+func (c command) applicationCommand() *discordgo.ApplicationCommand {
+	options := append(make([]*discordgo.ApplicationCommandOption, 0, len(c.options)+1), c.options...)
+	hasLeague := false
+	for _, o := range options {
+		hasLeague = hasLeague || o.Name == leagueOption
+	}
+	if !c.leagueless && !hasLeague {
+		options = append(options, &discordgo.ApplicationCommandOption{
+			Type:         discordgo.ApplicationCommandOptionString,
+			Name:         leagueOption,
+			Description:  "League to use, defaults to this channel's league",
+			Autocomplete: true,
+		})
+	}
+	ac := &discordgo.ApplicationCommand{
+		Name:        c.name,
+		Type:        discordgo.ChatApplicationCommand,
+		Description: c.description,
+		Options:     options,
+	}
+	if c.permissions != 0 {
+		permissions := c.permissions
+		ac.DefaultMemberPermissions = &permissions
+	}
+	return ac
+}
+
+// maxUserGuilds is the most guilds Discord lists at once.
+const maxUserGuilds = 100
+
+// allGuilds lists every guild the bot is in.  list returns a page of the
+// guilds after the given guild ID, which is empty for the first page.
+func allGuilds(list func(after string) ([]*discordgo.UserGuild, error)) ([]*discordgo.UserGuild, error) {
+	guilds := make([]*discordgo.UserGuild, 0)
+	after := ""
+	for {
+		page, err := list(after)
+		if err != nil {
+			return nil, err
+		}
+		guilds = append(guilds, page...)
+		if len(page) < maxUserGuilds {
+			return guilds, nil
+		}
+		after = page[len(page)-1].ID
+	}
+}
+
+// syncCommands replaces the bot's registered commands with commands, in each
+// of guildIDs or globally if there are none.  Commands registered the other
+// way, or in guilds no longer listed, are deleted so they don't show up twice
+// or linger after they're removed.
+func syncCommands(s *discordgo.Session, appID string, guildIDs []string) error {
+	registered := make([]*discordgo.ApplicationCommand, 0, len(commands))
+	for _, c := range commands {
+		registered = append(registered, c.applicationCommand())
+	}
+	none := make([]*discordgo.ApplicationCommand, 0)
+
+	synced := make(map[string]bool)
+	for _, g := range guildIDs {
+		if _, err := s.ApplicationCommandBulkOverwrite(appID, g, registered); err != nil {
+			return fmt.Errorf("guild %s: %w", g, err)
+		}
+		synced[g] = true
+	}
+	global := registered
+	if len(guildIDs) > 0 {
+		global = none
+	}
+	if _, err := s.ApplicationCommandBulkOverwrite(appID, "", global); err != nil {
+		return fmt.Errorf("global: %w", err)
+	}
+	log.Printf("registered %d commands in %d guild(s) and %d globally", len(registered), len(guildIDs), len(global))
+
+	guilds, err := allGuilds(func(after string) ([]*discordgo.UserGuild, error) {
+		return s.UserGuilds(maxUserGuilds, "", after)
+	})
+	if err != nil {
+		return fmt.Errorf("listing guilds: %w", err)
+	}
+	for _, g := range guilds {
+		if synced[g.ID] {
+			continue
+		}
+		stale, err := s.ApplicationCommands(appID, g.ID)
+		if err != nil {
+			log.Printf("error listing commands in guild %s: %s", g.ID, err)
+			continue
+		}
+		if len(stale) == 0 {
+			continue
+		}
+		log.Printf("deleting %d stale commands in guild %s", len(stale), g.ID)
+		if _, err := s.ApplicationCommandBulkOverwrite(appID, g.ID, none); err != nil {
+			log.Printf("error deleting commands in guild %s: %s", g.ID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// commandNamePattern is what Discord accepts as a slash command or option
+// name.
+var commandNamePattern = regexp.MustCompile(`^[-_a-z0-9]{1,32}$`)
+
+func TestCommands(t *testing.T) {
+	if len(commandsByName) != len(commands) {
+		t.Errorf("%d commands but %d names, want them unique", len(commands), len(commandsByName))
+	}
+	for _, c := range commands {
+		ac := c.applicationCommand()
+		if !commandNamePattern.MatchString(ac.Name) {
+			t.Errorf("command name %q isn't a valid Discord name", ac.Name)
+		}
+		if ac.Description == "" || len(ac.Description) > 100 {
+			t.Errorf("/%s description %q must be 1 to 100 characters", ac.Name, ac.Description)
+		}
+		if len(ac.Options) > 25 {
+			t.Errorf("/%s has %d options, want at most 25", ac.Name, len(ac.Options))
+		}
+		if c.handler == nil {
+			t.Errorf("/%s has no handler", ac.Name)
+		}
+		for _, o := range ac.Options {
+			if !commandNamePattern.MatchString(o.Name) {
+				t.Errorf("/%s option name %q isn't a valid Discord name", ac.Name, o.Name)
+			}
+			// the league option is autocompleted for every command
+			if o.Autocomplete && o.Name != leagueOption && c.autocomplete == nil {
+				t.Errorf("/%s autocompletes %s without an autocomplete handler", ac.Name, o.Name)
+			}
+		}
+	}
+}
+
+func TestApplicationCommand(t *testing.T) {
+	ownLeague := &discordgo.ApplicationCommandOption{Type: discordgo.ApplicationCommandOptionString, Name: leagueOption, Required: true}
+	week := &discordgo.ApplicationCommandOption{Type: discordgo.ApplicationCommandOptionInteger, Name: "week"}
+	tests := []struct {
+		name        string
+		c           command
+		wantOptions []string
+	}{
+		{"leagueless", command{name: "version", leagueless: true}, []string{}},
+		{"league added last", command{name: "standings", options: []*discordgo.ApplicationCommandOption{week}}, []string{"week", leagueOption}},
+		{"own league option", command{name: "defaultleague", options: []*discordgo.ApplicationCommandOption{ownLeague}}, []string{leagueOption}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ac := tt.c.applicationCommand()
+			got := make([]string, 0)
+			for _, o := range ac.Options {
+				got = append(got, o.Name)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.wantOptions) {
+				t.Errorf("applicationCommand() options = %q, want %q", got, tt.wantOptions)
+			}
+			if len(tt.c.options) > 0 && ac.Options[0] != tt.c.options[0] {
+				t.Errorf("applicationCommand() replaced option %s", tt.c.options[0].Name)
+			}
+		})
+	}
+
+	permissions := command{name: "debug", permissions: discordgo.PermissionManageServer}.applicationCommand()
+	if permissions.DefaultMemberPermissions == nil || *permissions.DefaultMemberPermissions != discordgo.PermissionManageServer {
+		t.Errorf("applicationCommand() permissions = %v, want %d", permissions.DefaultMemberPermissions, discordgo.PermissionManageServer)
+	}
+	if everyone := (command{name: "standings"}).applicationCommand(); everyone.DefaultMemberPermissions != nil {
+		t.Errorf("applicationCommand() permissions = %d, want none", *everyone.DefaultMemberPermissions)
+	}
+}
+
+func TestAllGuilds(t *testing.T) {
+	// testGuilds lists n guilds with IDs 1 to n, checking each page starts
+	// after the last one
+	testGuilds := func(n int) (func(after string) ([]*discordgo.UserGuild, error), *[]string) {
+		pages := make([]string, 0)
+		return func(after string) ([]*discordgo.UserGuild, error) {
+			pages = append(pages, after)
+			start := 0
+			if after != "" {
+				fmt.Sscan(after, &start)
+			}
+			page := make([]*discordgo.UserGuild, 0)
+			for id := start + 1; id <= n && len(page) < maxUserGuilds; id++ {
+				page = append(page, &discordgo.UserGuild{ID: fmt.Sprint(id)})
+			}
+			return page, nil
+		}, &pages
+	}
+	tests := []struct {
+		guilds    int
+		wantPages []string
+	}{
+		{0, []string{""}},
+		{3, []string{""}},
+		{maxUserGuilds, []string{"", "100"}},
+		{250, []string{"", "100", "200"}},
+	}
+	for _, tt := range tests {
+		list, pages := testGuilds(tt.guilds)
+		got, err := allGuilds(list)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != tt.guilds {
+			t.Errorf("allGuilds() with %d guilds listed %d", tt.guilds, len(got))
+		}
+		if fmt.Sprint(*pages) != fmt.Sprint(tt.wantPages) {
+			t.Errorf("allGuilds() with %d guilds listed pages after %q, want %q", tt.guilds, *pages, tt.wantPages)
+		}
+	}
+
+	failing := func(after string) ([]*discordgo.UserGuild, error) {
+		return nil, errors.New("rate limited")
+	}
+	if _, err := allGuilds(failing); err == nil {
+		t.Error("allGuilds() with a failing list = nil error, want one")
+	}
+}
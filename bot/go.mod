@@ -3,20 +3,32 @@ module github.com/craigatron/football-gobot/bot
 go 1.19
 
 require (
-	cloud.google.com/go/firestore v1.6.1
+	cloud.google.com/go/storage v1.26.0
 	github.com/bwmarrin/discordgo v0.26.1
-	github.com/craigatron/espn-fantasy-go v0.0.2-0.20220731182059-c6130f269bb2
+	github.com/craigatron/football-gobot/cf/update-activity v0.0.0
+	github.com/craigatron/football-gobot/cf/update-scores v0.0.0
 	github.com/craigatron/football-gobot/config v0.0.0
-	google.golang.org/api v0.96.0
+	github.com/craigatron/football-gobot/store v0.0.0
+	golang.org/x/image v0.0.0-20220902085622-e7cb96979f69
 )
 
+replace github.com/craigatron/football-gobot/cf/update-activity => ../cf/update-activity
+
+replace github.com/craigatron/football-gobot/cf/update-scores => ../cf/update-scores
+
 replace github.com/craigatron/football-gobot/config => ../config
 
+replace github.com/craigatron/football-gobot/store => ../store
+
 require (
 	cloud.google.com/go v0.104.0 // indirect
 	cloud.google.com/go/compute v1.10.0 // indirect
+	cloud.google.com/go/firestore v1.6.1 // indirect
+	cloud.google.com/go/functions v1.0.0 // indirect
 	cloud.google.com/go/iam v0.4.0 // indirect
-	cloud.google.com/go/storage v1.26.0 // indirect
+	github.com/GoogleCloudPlatform/functions-framework-go v1.5.3 // indirect
+	github.com/cloudevents/sdk-go/v2 v2.6.1 // indirect
+	github.com/craigatron/espn-fantasy-go v0.0.2-0.20220908003037-8c46118e0ace // indirect
 	github.com/craigatron/sleeper-go v0.0.0-20220907013444-753ab69ad51f // indirect
 	github.com/golang/groupcache v0.0.0-20210331224755-41bb18bfe9da // indirect
 	github.com/golang/protobuf v1.5.2 // indirect
@@ -25,13 +37,21 @@ require (
 	github.com/googleapis/enterprise-certificate-proxy v0.1.0 // indirect
 	github.com/googleapis/gax-go/v2 v2.5.1 // indirect
 	github.com/gorilla/websocket v1.5.0 // indirect
+	github.com/json-iterator/go v1.1.10 // indirect
+	github.com/mattn/go-sqlite3 v1.14.15 // indirect
+	github.com/modern-go/concurrent v0.0.0-20180228061459-e0a39a4cb421 // indirect
+	github.com/modern-go/reflect2 v0.0.0-20180701023420-4b7aa43c6742 // indirect
 	go.opencensus.io v0.23.0 // indirect
+	go.uber.org/atomic v1.4.0 // indirect
+	go.uber.org/multierr v1.1.0 // indirect
+	go.uber.org/zap v1.10.0 // indirect
 	golang.org/x/crypto v0.0.0-20220919173607-35f4265a4bc0 // indirect
 	golang.org/x/net v0.0.0-20220919232410-f2f64ebce3c1 // indirect
 	golang.org/x/oauth2 v0.0.0-20220909003341-f21342109be1 // indirect
 	golang.org/x/sys v0.0.0-20220919091848-fb04ddd9f9c8 // indirect
 	golang.org/x/text v0.3.7 // indirect
 	golang.org/x/xerrors v0.0.0-20220907171357-04be3eba64a2 // indirect
+	google.golang.org/api v0.96.0 // indirect
 	google.golang.org/appengine v1.6.7 // indirect
 	google.golang.org/genproto v0.0.0-20220919141832-68c03719ef51 // indirect
 	google.golang.org/grpc v1.49.0 // indirect
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	updateactivity "github.com/craigatron/football-gobot/cf/update-activity"
+	updatescores "github.com/craigatron/football-gobot/cf/update-scores"
+	"github.com/craigatron/football-gobot/config"
+)
+
+// job is an update job the bot runs on a schedule instead of as a Cloud
+// Function.
+type job struct {
+	name     string
+	schedule config.Schedule
+	run      func(ctx context.Context) error
+	// running is held for the length of a run so runs never overlap.
+	running sync.Mutex
+}
+
+// start runs the job on its schedule forever.  Each run happens in the
+// background, so a run that's still going when the next is due causes that
+// next run to be skipped rather than delayed.
+func (j *job) start() {
+	log.Printf("scheduling %s", j.name)
+	for {
+		next := j.schedule.Next(time.Now())
+		if next.IsZero() {
+			log.Printf("%s schedule never runs again, stopping", j.name)
+			return
+		}
+		time.Sleep(time.Until(next))
+		go j.runOnce()
+	}
+}
+
+// runOnce runs the job unless it's already running, logging how long the run
+// took and whether it failed.
+func (j *job) runOnce() {
+	if !j.running.TryLock() {
+		log.Printf("skipping %s run, previous run is still going", j.name)
+		return
+	}
+	defer j.running.Unlock()
+
+	start := time.Now()
+	log.Printf("starting %s run", j.name)
+	if err := j.run(context.Background()); err != nil {
+		log.Printf("%s run failed after %s: %s", j.name, time.Since(start), err)
+		return
+	}
+	log.Printf("%s run succeeded in %s", j.name, time.Since(start))
+}
+
+// startJobs starts the update jobs scheduled in the config.  Jobs always run
+// against the current config's leagues, but schedules and the chart output
+// are only read at startup.
+func startJobs(s *discordgo.Session, c config.JobsConfigJSON, outputConfig config.OutputConfigJSON) error {
+	jobs := make([]*job, 0)
+
+	if c.UpdateActivity != "" {
+		schedule, err := c.Schedule(c.UpdateActivity)
+		if err != nil {
+			return err
+		}
+		jobs = append(jobs, &job{
+			name:     "update-activity",
+			schedule: schedule,
+			run: func(ctx context.Context) error {
+				return updateactivity.Run(ctx, db, currentState().leagues())
+			},
+		})
+	}
+
+	scoresSchedule, err := c.ScoresSchedule()
+	if err != nil {
+		return err
+	}
+	if scoresSchedule != nil {
+		output, err := updatescores.NewOutput(context.Background(), outputConfig)
+		if err != nil {
+			return err
+		}
+		jobs = append(jobs, &job{
+			name:     "update-scores",
+			schedule: scoresSchedule,
+			run: func(ctx context.Context) error {
+				return updatescores.Run(ctx, db, currentState().leagues(), output)
+			},
+		})
+	}
+
+	if c.BuildSite != "" {
+		schedule, err := c.Schedule(c.BuildSite)
+		if err != nil {
+			return err
+		}
+		output, err := updatescores.NewOutput(context.Background(), outputConfig)
+		if err != nil {
+			return err
+		}
+		jobs = append(jobs, &job{
+			name:     "build-site",
+			schedule: schedule,
+			run: func(ctx context.Context) error {
+				return updatescores.RunSite(ctx, db, currentState().leagues(), output)
+			},
+		})
+	}
+
+	if c.Recap != "" {
+		schedule, err := c.Schedule(c.Recap)
+		if err != nil {
+			return err
+		}
+		jobs = append(jobs, &job{
+			name:     "recap",
+			schedule: schedule,
+			run: func(ctx context.Context) error {
+				return postRecaps(ctx, s)
+			},
+		})
+	}
+
+	for _, j := range jobs {
+		go j.start()
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/craigatron/football-gobot/config"
+)
+
+// leagueOption is added to every command that uses a league, so it can be
+// used outside the league's categories.
+const leagueOption = "league"
+
+// optionValue returns the string value of a top-level command option, or ""
+// if it isn't set.
+func optionValue(i *discordgo.InteractionCreate, name string) string {
+	for _, o := range i.ApplicationCommandData().Options {
+		if o.Name == name {
+			return o.StringValue()
+		}
+	}
+	return ""
+}
+
+// focusedOption returns the name of the option being autocompleted.
+func focusedOption(i *discordgo.InteractionCreate) string {
+	for _, o := range i.ApplicationCommandData().Options {
+		if o.Focused {
+			return o.Name
+		}
+	}
+	return ""
+}
+
+// leagueName is how a league is shown to users.
+func leagueName(league config.League) string {
+	if name := league.Config().Name; name != "" {
+		return name
+	}
+	return fmt.Sprintf("%s league %s", league.Type(), league.ID())
+}
+
+// commandLeague picks the league a command is for: the one picked in the
+// league option, the one mapped to the channel's category, the server's
+// default league, or the only league the user is linked to, in that order.
+// If there isn't one, it returns a message telling the user what to do.
+func commandLeague(ctx context.Context, i *discordgo.InteractionCreate, channel *discordgo.Channel) (config.League, string) {
+	st := currentState()
+	if key := optionValue(i, leagueOption); key != "" {
+		if league, ok := st.leaguesByKey[key]; ok {
+			return league, ""
+		}
+		return nil, "pick a league from the list"
+	}
+	if league, ok := st.leaguesByCategory[channel.ParentID]; ok {
+		return league, ""
+	}
+	if i.GuildID != "" {
+		key, err := db.DefaultLeague(ctx, i.GuildID)
+		if err != nil {
+			log.Printf("error getting default league for guild %s: %s", i.GuildID, err)
+		} else if league, ok := st.leaguesByKey[key]; ok {
+			return league, ""
+		}
+	}
+
+	linked := make([]config.League, 0)
+	for _, league := range st.leagues() {
+		if _, ok := linkedTeam(league, interactionUser(i).ID); ok {
+			linked = append(linked, league)
+		}
+	}
+	switch {
+	case len(linked) == 1:
+		return linked[0], ""
+	case len(linked) > 1:
+		return nil, fmt.Sprintf("you're in %d leagues, pick one with the league option", len(linked))
+	case i.GuildID == "":
+		return nil, "pick a league with the league option"
+	}
+	return nil, "this channel isn't in a league's category, pick a league with the league option or ask a server admin to set a default with /defaultleague"
+}
+
+// handleLeagueAutocomplete suggests leagues whose names contain what's been
+// typed so far in the league option.
+func handleLeagueAutocomplete(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	typed := strings.ToLower(optionValue(i, leagueOption))
+	leagues := make([]config.League, 0)
+	for _, league := range currentState().leagues() {
+		if strings.Contains(strings.ToLower(leagueName(league)), typed) {
+			leagues = append(leagues, league)
+		}
+	}
+	sort.Slice(leagues, func(i, j int) bool {
+		return leagueName(leagues[i]) < leagueName(leagues[j])
+	})
+
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, 0, len(leagues))
+	for _, league := range leagues {
+		if len(choices) == maxAutocompleteChoices {
+			break
+		}
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+			Name:  leagueName(league),
+			Value: config.LeagueKey(league),
+		})
+	}
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionApplicationCommandAutocompleteResult,
+		Data: &discordgo.InteractionResponseData{
+			Choices: choices,
+		},
+	})
+	if err != nil {
+		log.Printf("error responding to autocomplete: %s", err)
+	}
+}
+
+func handleDefaultLeagueCommand(s *discordgo.Session, i *discordgo.InteractionCreate, league config.League, channel *discordgo.Channel) {
+	if i.GuildID == "" {
+		respondPrivately(s, i, "default leagues can only be set in a server")
+		return
+	}
+	if err := db.SetDefaultLeague(context.Background(), i.GuildID, league); err != nil {
+		log.Printf("error setting default league for guild %s: %s", i.GuildID, err)
+		respondPrivately(s, i, "could not set the default league")
+		return
+	}
+	respondPrivately(s, i, fmt.Sprintf("commands outside league categories in this server now use %s", leagueName(league)))
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/craigatron/football-gobot/config"
+	"github.com/craigatron/football-gobot/config/configtest"
+	"github.com/craigatron/football-gobot/store"
+)
+
+// testInteraction is a command from userID in guildID, or a DM if guildID is
+// empty, with the league option set to leagueKey if it isn't empty.
+func testInteraction(guildID string, userID string, leagueKey string) *discordgo.InteractionCreate {
+	options := make([]*discordgo.ApplicationCommandInteractionDataOption, 0)
+	if leagueKey != "" {
+		options = append(options, &discordgo.ApplicationCommandInteractionDataOption{
+			Name:  leagueOption,
+			Type:  discordgo.ApplicationCommandOptionString,
+			Value: leagueKey,
+		})
+	}
+	i := &discordgo.Interaction{
+		Type:    discordgo.InteractionApplicationCommand,
+		GuildID: guildID,
+		Data:    discordgo.ApplicationCommandInteractionData{Name: "standings", Options: options},
+	}
+	user := &discordgo.User{ID: userID}
+	if guildID == "" {
+		i.User = user
+	} else {
+		i.Member = &discordgo.Member{User: user}
+	}
+	return &discordgo.InteractionCreate{Interaction: i}
+}
+
+func TestCommandLeague(t *testing.T) {
+	teams := map[int64]config.Team{1: {ID: 1, OwnerIDs: []string{"owner"}}}
+	work := configtest.League{
+		LeagueType: config.LeagueTypeESPN,
+		TeamsByID:  teams,
+		Settings:   config.LeagueConfigJSON{Links: map[string]string{"both": "owner"}},
+	}
+	family := configtest.League{LeagueID: "2", TeamsByID: teams}
+	dynasty := configtest.League{
+		LeagueID:  "3",
+		TeamsByID: teams,
+		Settings:  config.LeagueConfigJSON{Links: map[string]string{"dynasty": "owner", "both": "owner"}},
+	}
+	state.Store(&botState{
+		config:            &config.JSON{},
+		leaguesByCategory: map[string]config.League{"work-category": work},
+		leaguesByKey: map[string]config.League{
+			config.LeagueKey(work):    work,
+			config.LeagueKey(family):  family,
+			config.LeagueKey(dynasty): dynasty,
+		},
+	})
+	db = store.NewMemory()
+	if err := db.SetDefaultLeague(context.Background(), "family-guild", family); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		i           *discordgo.InteractionCreate
+		category    string
+		want        config.League
+		wantProblem string
+	}{
+		{
+			name:     "league option over everything",
+			i:        testInteraction("family-guild", "dynasty", config.LeagueKey(work)),
+			category: "work-category",
+			want:     work,
+		},
+		{
+			name:        "unknown league option",
+			i:           testInteraction("family-guild", "dynasty", "leagues/espn-9"),
+			category:    "work-category",
+			wantProblem: "pick a league from the list",
+		},
+		{
+			name:     "category over the server default",
+			i:        testInteraction("family-guild", "dynasty", ""),
+			category: "work-category",
+			want:     work,
+		},
+		{
+			name:     "server default over links",
+			i:        testInteraction("family-guild", "dynasty", ""),
+			category: "other-category",
+			want:     family,
+		},
+		{
+			name: "only linked league",
+			i:    testInteraction("other-guild", "dynasty", ""),
+			want: dynasty,
+		},
+		{
+			name: "only linked league in a DM",
+			i:    testInteraction("", "dynasty", ""),
+			want: dynasty,
+		},
+		{
+			name:        "linked to several leagues",
+			i:           testInteraction("", "both", ""),
+			wantProblem: "you're in 2 leagues, pick one with the league option",
+		},
+		{
+			name:        "nothing to go on in a DM",
+			i:           testInteraction("", "stranger", ""),
+			wantProblem: "pick a league with the league option",
+		},
+		{
+			name:        "nothing to go on in a server",
+			i:           testInteraction("other-guild", "stranger", ""),
+			wantProblem: "this channel isn't in a league's category, pick a league with the league option or ask a server admin to set a default with /defaultleague",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, problem := commandLeague(context.Background(), tt.i, &discordgo.Channel{ParentID: tt.category})
+			if problem != tt.wantProblem {
+				t.Errorf("commandLeague() problem = %q, want %q", problem, tt.wantProblem)
+			}
+			if (got == nil) != (tt.want == nil) || (got != nil && config.LeagueKey(got) != config.LeagueKey(tt.want)) {
+				t.Errorf("commandLeague() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
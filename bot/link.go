@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/craigatron/football-gobot/config"
+	"github.com/craigatron/football-gobot/store"
+)
+
+// resolveLink finds the linked team, preferring the owner ID since team IDs
+// can be reassigned between seasons.
+func resolveLink(teams map[int64]config.Team, link store.Link) (config.Team, bool) {
+	if link.OwnerID != "" {
+		for _, t := range teams {
+			for _, o := range t.OwnerIDs {
+				if o == link.OwnerID {
+					return t, true
+				}
+			}
+		}
+	}
+	t, ok := teams[link.TeamID]
+	return t, ok
+}
+
+// linkedTeam returns the team linked to a Discord user, either with /link or
+// in the league's config.
+func linkedTeam(league config.League, userID string) (config.Team, bool) {
+	teams := league.Teams()
+	link, ok, err := db.Link(context.Background(), league, userID)
+	if err != nil {
+		log.Printf("error getting link for user %s: %s", userID, err)
+	}
+	if !ok {
+		ownerID, seeded := league.Config().Links[userID]
+		if !seeded {
+			return config.Team{}, false
+		}
+		link = store.Link{OwnerID: ownerID}
+	}
+	return resolveLink(teams, link)
+}
+
+// leagueLinks returns every Discord user's link in the league, made with
+// /link or seeded in the league's config.
+func leagueLinks(ctx context.Context, league config.League) (map[string]store.Link, error) {
+	links, err := db.Links(ctx, league)
+	if err != nil {
+		return nil, err
+	}
+	for userID, ownerID := range league.Config().Links {
+		if _, ok := links[userID]; !ok {
+			links[userID] = store.Link{OwnerID: ownerID}
+		}
+	}
+	return links, nil
+}
+
+// teamMentions maps team IDs to mentions of every Discord user linked to them.
+func teamMentions(league config.League) map[int64]string {
+	teams := league.Teams()
+	links, err := leagueLinks(context.Background(), league)
+	if err != nil {
+		log.Printf("error getting links: %s", err)
+		links = make(map[string]store.Link)
+	}
+
+	mentions := make(map[int64]string)
+	for userID, link := range links {
+		if t, ok := resolveLink(teams, link); ok {
+			if mentions[t.ID] != "" {
+				mentions[t.ID] += " "
+			}
+			mentions[t.ID] += fmt.Sprintf("<@%s>", userID)
+		}
+	}
+	return mentions
+}
+
+// interactionUser is the user who triggered the interaction, whether it came
+// from a guild or a DM.
+func interactionUser(i *discordgo.InteractionCreate) *discordgo.User {
+	if i.Member != nil {
+		return i.Member.User
+	}
+	return i.User
+}
+
+// isAdmin reports whether the interaction came from a server admin.
+func isAdmin(i *discordgo.InteractionCreate) bool {
+	return i.Member != nil && i.Member.Permissions&(discordgo.PermissionAdministrator|discordgo.PermissionManageServer) != 0
+}
+
+func handleLinkCommand(s *discordgo.Session, i *discordgo.InteractionCreate, league config.League, channel *discordgo.Channel) {
+	userID := interactionUser(i).ID
+	var teamID int64
+	for _, o := range i.ApplicationCommandData().Options {
+		switch o.Name {
+		case "team":
+			teamID, _ = strconv.ParseInt(o.StringValue(), 10, 64)
+		case "user":
+			userID = o.UserValue(nil).ID
+		}
+	}
+
+	if userID != interactionUser(i).ID && !isAdmin(i) {
+		respondPrivately(s, i, "only server admins can link other users")
+		return
+	}
+
+	teams := league.Teams()
+	team, ok := teams[teamID]
+	if !ok {
+		respondPrivately(s, i, "pick a team from the list")
+		return
+	}
+
+	links, err := leagueLinks(context.Background(), league)
+	if err != nil {
+		log.Printf("error getting links: %s", err)
+		respondPrivately(s, i, "could not link team")
+		return
+	}
+	for otherID, link := range links {
+		if t, ok := resolveLink(teams, link); ok && t.ID == team.ID && otherID != userID && !isAdmin(i) {
+			respondPrivately(s, i, fmt.Sprintf("%s is already linked to <@%s>, ask a server admin to change it", team.Name, otherID))
+			return
+		}
+	}
+
+	link := store.Link{TeamID: team.ID}
+	if len(team.OwnerIDs) > 0 {
+		link.OwnerID = team.OwnerIDs[0]
+	}
+	if err := db.SetLink(context.Background(), league, userID, link); err != nil {
+		log.Printf("error setting link: %s", err)
+		respondPrivately(s, i, "could not link team")
+		return
+	}
+	respondPrivately(s, i, fmt.Sprintf("linked <@%s> to %s", userID, team.Name))
+}
+
+func handleWhoamiCommand(s *discordgo.Session, i *discordgo.InteractionCreate, league config.League, channel *discordgo.Channel) {
+	content := "you're not linked to a team in this league, use /link to pick yours"
+	if team, ok := linkedTeam(league, interactionUser(i).ID); ok {
+		content = fmt.Sprintf("you're %s in %s", team.Name, league.Config().Name)
+	}
+	respondPrivately(s, i, content)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/craigatron/football-gobot/config"
+	"github.com/craigatron/football-gobot/config/configtest"
+	"github.com/craigatron/football-gobot/store"
+)
+
+func TestResolveLink(t *testing.T) {
+	// team 2 was team 1's owner's team last season
+	teams := map[int64]config.Team{
+		1: {ID: 1, Name: "One", OwnerIDs: []string{"new-owner"}},
+		2: {ID: 2, Name: "Two", OwnerIDs: []string{"co-owner", "owner"}},
+	}
+	tests := []struct {
+		name   string
+		link   store.Link
+		want   int64
+		wantOK bool
+	}{
+		{"owner", store.Link{OwnerID: "owner"}, 2, true},
+		{"co-owner", store.Link{OwnerID: "co-owner"}, 2, true},
+		{"owner over team", store.Link{TeamID: 1, OwnerID: "owner"}, 2, true},
+		{"team when the owner left", store.Link{TeamID: 1, OwnerID: "gone"}, 1, true},
+		{"team without an owner", store.Link{TeamID: 1}, 1, true},
+		{"unknown team", store.Link{TeamID: 3}, 0, false},
+		{"nothing linked", store.Link{}, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := resolveLink(teams, tt.link)
+			if ok != tt.wantOK || got.ID != tt.want {
+				t.Errorf("resolveLink(%+v) = %d, %t, want %d, %t", tt.link, got.ID, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestLinkedTeam(t *testing.T) {
+	league := configtest.League{
+		TeamsByID: map[int64]config.Team{
+			1: {ID: 1, OwnerIDs: []string{"owner-1"}},
+			2: {ID: 2, OwnerIDs: []string{"owner-2"}},
+		},
+		Settings: config.LeagueConfigJSON{Links: map[string]string{"seeded": "owner-1", "relinked": "owner-1"}},
+	}
+	db = store.NewMemory()
+	if err := db.SetLink(context.Background(), league, "relinked", store.Link{TeamID: 2, OwnerID: "owner-2"}); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		userID string
+		want   int64
+		wantOK bool
+	}{
+		{"seeded", 1, true},
+		// /link takes precedence over the config
+		{"relinked", 2, true},
+		{"stranger", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := linkedTeam(league, tt.userID)
+		if ok != tt.wantOK || got.ID != tt.want {
+			t.Errorf("linkedTeam(%q) = %d, %t, want %d, %t", tt.userID, got.ID, ok, tt.want, tt.wantOK)
+		}
+	}
+}
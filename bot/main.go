@@ -1,7 +1,9 @@
 package main
 
 import (
+	"context"
 	_ "embed"
+	"flag"
 	"fmt"
 	"log"
 	"os"
@@ -13,48 +15,62 @@ import (
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/craigatron/football-gobot/config"
+	"github.com/craigatron/football-gobot/store"
 )
 
 var botID string
-var botConfig *config.JSON
-var leaguesByCategory map[string]*config.LeagueClient
+
+// db stores league data shared with the update jobs.  It's opened once at
+// startup, so changing the storage config requires a restart.
+var db store.Store
 
 var buildCommit string
 var buildDate string
 
 func main() {
+	configPath := flag.String("config", "", "path to a local config file, overrides CONFIG_FILE and GCS")
+	flag.Parse()
+
 	log.Printf("build at commit %s on %s", buildCommit, buildDate)
 
-	bc, err := config.LoadConfig()
+	configSource, err := config.DefaultSource(*configPath)
+	if err != nil {
+		log.Fatalf("Could not find config: %s", err)
+	}
+	log.Printf("loading config from %s", configSource)
+	configVersion, err := configSource.Version(context.Background())
+	if err != nil {
+		log.Fatalf("Could not get config version: %s", err)
+	}
+	bc, err := config.LoadBotConfigFrom(context.Background(), configSource)
 	if err != nil {
 		log.Fatalf("Could not load config file: %s", err)
 	}
-	botConfig = &bc
 
-	err = loadLeagues()
+	st, err := newBotState(&bc, nil)
 	if err != nil {
 		log.Fatalf("Error initializing FFL leagues: %s", err)
 	}
-
-	for _, espnLeague := range leaguesByCategory {
-		if espnLeague.LeagueType == config.LeagueTypeESPN {
-			go func() {
-				for range time.Tick(time.Hour) {
-					fmt.Printf("refreshing ESPN league: %s", espnLeague.ESPNLeague.ID)
-					if err := espnLeague.ESPNLeague.RefreshData(); err != nil {
-						fmt.Printf("error refreshing ESPN data: %s", err)
-					}
+	state.Store(st)
+
+	go func() {
+		for range time.Tick(time.Hour) {
+			for _, league := range currentState().leagues() {
+				log.Printf("refreshing %s league: %s", league.Type(), league.ID())
+				if err := league.Refresh(); err != nil {
+					log.Printf("error refreshing %s data: %s", league.Type(), err)
 				}
-			}()
+			}
 		}
-	}
+	}()
 
-	err = initFirestoreClient()
+	db, err = store.New(context.Background(), bc.StorageConfig)
 	if err != nil {
-		log.Fatalf("error initializing firestore client: %s", err)
+		log.Fatalf("error opening %q store: %s", bc.StorageConfig.Type, err)
 	}
+	defer db.Close()
 
-	dg, err := discordgo.New("Bot " + botConfig.Token)
+	dg, err := discordgo.New("Bot " + bc.Token)
 	if err != nil {
 		log.Fatalf("Error creating Discord session: %s", err)
 	}
@@ -67,50 +83,29 @@ func main() {
 
 	dg.AddHandler(messageHandler)
 
-	command := &discordgo.ApplicationCommand{
-		Name:        "bot-version",
-		Type:        discordgo.ChatApplicationCommand,
-		Description: "See what version of FOOTBALL GOBOT is active",
-	}
-	_, err = dg.ApplicationCommandCreate(botConfig.AppID, "", command)
-	if err != nil {
-		log.Fatalf("Error creating application command: %s", err)
-	}
-	command = &discordgo.ApplicationCommand{
-		Name:        "debug",
-		Type:        discordgo.ChatApplicationCommand,
-		Description: "Get debug info about GOBOT",
-	}
-	_, err = dg.ApplicationCommandCreate(botConfig.AppID, "", command)
-	if err != nil {
-		log.Fatalf("Error creating application command: %s", err)
-	}
-	command = &discordgo.ApplicationCommand{
-		Name:        "activity",
-		Type:        discordgo.ChatApplicationCommand,
-		Description: "Show recent activity for this league",
-	}
-	_, err = dg.ApplicationCommandCreate(botConfig.AppID, "", command)
-	if err != nil {
-		log.Fatalf("Error creating application command: %s", err)
-	}
-	command = &discordgo.ApplicationCommand{
-		Name:        "charts",
-		Type:        discordgo.ChatApplicationCommand,
-		Description: "Get link to current projections charts",
-	}
-	_, err = dg.ApplicationCommandCreate(botConfig.AppID, "", command)
-	if err != nil {
-		log.Fatalf("Error creating application command: %s", err)
+	if err := syncCommands(dg, bc.AppID, bc.CommandGuildIDs); err != nil {
+		log.Fatalf("Error registering application commands: %s", err)
 	}
 
 	dg.AddHandler(commandHandler)
+	dg.AddHandler(componentHandler)
 
 	err = dg.Open()
 	if err != nil {
 		log.Fatalf("Error opening connection: %s", err)
 	}
 
+	if interval := configReloadInterval(); interval > 0 {
+		go watchConfig(dg, configSource, configVersion, interval)
+	}
+
+	go notifyActivity(dg)
+	go notifyResults(dg)
+
+	if err := startJobs(dg, bc.JobsConfig, bc.OutputConfig); err != nil {
+		log.Fatalf("Error scheduling jobs: %s", err)
+	}
+
 	log.Println("FOOTBALL GOBOT ONLINE")
 	sc := make(chan os.Signal, 1)
 	signal.Notify(sc, syscall.SIGINT, syscall.SIGTERM, os.Interrupt)
@@ -120,21 +115,6 @@ func main() {
 	dg.Close()
 }
 
-func loadLeagues() error {
-	leagues, err := config.CreateLeagueClients(*botConfig)
-	if err != nil {
-		return err
-	}
-
-	leaguesByCategory = make(map[string]*config.LeagueClient)
-	for _, v := range leagues {
-		for _, d := range v.LeagueConfig.DiscordCategoryIDs {
-			leaguesByCategory[d] = v
-		}
-	}
-	return nil
-}
-
 func messageHandler(s *discordgo.Session, m *discordgo.MessageCreate) {
 	if m.Author.ID == botID {
 		return
@@ -156,6 +136,7 @@ func checkReaccs(s *discordgo.Session, m *discordgo.MessageCreate) {
 	message := strings.ToLower(m.Content)
 	message = ignoreMessageRe.ReplaceAllString(message, "")
 
+	botConfig := currentState().config
 	for _, reacc := range botConfig.ReaccConfig.Reaccs {
 		match, _ := regexp.MatchString(reacc.Pattern, message)
 		if match {
@@ -172,78 +153,79 @@ func checkReaccs(s *discordgo.Session, m *discordgo.MessageCreate) {
 }
 
 func commandHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	if i.Type != discordgo.InteractionApplicationCommand {
+	if i.Type != discordgo.InteractionApplicationCommand && i.Type != discordgo.InteractionApplicationCommandAutocomplete {
 		return
 	}
 
-	channel, err := s.Channel(i.ChannelID)
-	if err != nil {
-		log.Printf("error getting channel: %s", err)
+	data := i.ApplicationCommandData()
+	c, ok := commandsByName[data.Name]
+	if !ok {
+		log.Printf("unknown command %s", data.Name)
 		return
 	}
-
-	league, ok := leaguesByCategory[channel.ParentID]
-	if !ok {
-		log.Printf("no league mapped for channel %s with category ID %s", channel.ID, channel.ParentID)
+	if i.Type == discordgo.InteractionApplicationCommandAutocomplete {
+		switch {
+		case focusedOption(i) == leagueOption:
+			handleLeagueAutocomplete(s, i)
+		case c.autocomplete != nil:
+			if league, _, problem := commandContext(c, s, i); problem == "" {
+				c.autocomplete(s, i, league)
+			}
+		}
 		return
 	}
+	runCommand(c, s, i)
+}
 
-	data := i.ApplicationCommandData()
-	switch data.Name {
-	case "bot-version":
-		handleBotVersionCommand(s, i)
-	case "debug":
-		handleDebugCommand(s, i, league, channel)
-	case "activity":
-		handleActivityCommand(s, i, league, channel)
-	case "charts":
-		handleChartsCommand(s, i, league, channel)
+// commandContext looks up the channel a command was used in and, unless the
+// command is leagueless, the league it's for.  If either can't be found it
+// returns the problem to tell the user instead.
+func commandContext(c command, s *discordgo.Session, i *discordgo.InteractionCreate) (config.League, *discordgo.Channel, string) {
+	channel, err := s.Channel(i.ChannelID)
+	if err != nil {
+		log.Printf("error getting channel: %s", err)
+		return nil, nil, "could not look up this channel, try again"
+	}
+	if c.leagueless {
+		return nil, channel, ""
 	}
+	league, problem := commandLeague(context.Background(), i, channel)
+	if league == nil {
+		log.Printf("no league for /%s in channel %s with category ID %s: %s", c.name, channel.ID, channel.ParentID, problem)
+		return nil, channel, problem
+	}
+	return league, channel, ""
 }
 
 func handleBotVersionCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-		Type: discordgo.InteractionResponseChannelMessageWithSource,
-		Data: &discordgo.InteractionResponseData{
-			Embeds: []*discordgo.MessageEmbed{
-				{
-					Title:       "FOOTBALL GOBOT",
-					URL:         fmt.Sprintf("https://github.com/craigatron/football-gobot/tree/%s", buildCommit),
-					Description: fmt.Sprintf("Built %s at commit hash %s", buildDate, buildCommit),
-				},
+	respond(s, i, &discordgo.InteractionResponseData{
+		Embeds: []*discordgo.MessageEmbed{
+			{
+				Title:       "FOOTBALL GOBOT",
+				URL:         fmt.Sprintf("https://github.com/craigatron/football-gobot/tree/%s", buildCommit),
+				Description: fmt.Sprintf("Built %s at commit hash %s", buildDate, buildCommit),
 			},
 		},
 	})
 }
 
-func handleDebugCommand(s *discordgo.Session, i *discordgo.InteractionCreate, league *config.LeagueClient, channel *discordgo.Channel) {
-	var leagueID string
-	if league.LeagueType == config.LeagueTypeESPN {
-		leagueID = league.ESPNLeague.ID
-	} else if league.LeagueType == config.LeagueTypeSleeper {
-		leagueID = league.SleeperLeague.ID
-	} else {
-		leagueID = "N/A"
-	}
-	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-		Type: discordgo.InteractionResponseChannelMessageWithSource,
-		Data: &discordgo.InteractionResponseData{
-			Embeds: []*discordgo.MessageEmbed{
-				{
-					Description: "debug info",
-					Fields: []*discordgo.MessageEmbedField{
-						{
-							Name:  "Discord Category ID",
-							Value: channel.ParentID,
-						},
-						{
-							Name:  "League Type",
-							Value: league.LeagueType.String(),
-						},
-						{
-							Name:  "League ID",
-							Value: leagueID,
-						},
+func handleDebugCommand(s *discordgo.Session, i *discordgo.InteractionCreate, league config.League, channel *discordgo.Channel) {
+	respond(s, i, &discordgo.InteractionResponseData{
+		Embeds: []*discordgo.MessageEmbed{
+			{
+				Description: "debug info",
+				Fields: []*discordgo.MessageEmbedField{
+					{
+						Name:  "Discord Category ID",
+						Value: channel.ParentID,
+					},
+					{
+						Name:  "League Type",
+						Value: league.Type().String(),
+					},
+					{
+						Name:  "League ID",
+						Value: league.ID(),
 					},
 				},
 			},
@@ -251,87 +233,48 @@ func handleDebugCommand(s *discordgo.Session, i *discordgo.InteractionCreate, le
 	})
 }
 
-func handleActivityCommand(s *discordgo.Session, i *discordgo.InteractionCreate, league *config.LeagueClient, channel *discordgo.Channel) {
-	if league.LeagueType != config.LeagueTypeESPN {
-		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-			Type: discordgo.InteractionResponseChannelMessageWithSource,
-			Data: &discordgo.InteractionResponseData{
-				Content: fmt.Sprintf("/activity not implemented for league type %s", league.LeagueType),
-			},
-		})
-		return
-	}
-	fmt.Printf("handling activity command for league %v and channel %v", *league, *channel)
-	recentActivity, err := getRecentESPNActivity(league.ESPNLeague)
+func handleChartsCommand(s *discordgo.Session, i *discordgo.InteractionCreate, league config.League, channel *discordgo.Channel) {
+	week, err := league.CurrentWeek()
 	if err != nil {
-		log.Printf("error getting recent activity: %s\n", err)
-		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-			Type: discordgo.InteractionResponseChannelMessageWithSource,
-			Data: &discordgo.InteractionResponseData{
-				Content: "could not get recent activity for league",
-			},
-		})
+		respondError(s, i, fmt.Sprintf("could not get %s league status", league.Type()), err)
 		return
 	}
-	fields := make([]*discordgo.MessageEmbedField, 0)
-	for _, ra := range recentActivity {
-		actionStrings := make([]string, 0)
-		for _, action := range ra.Actions {
-			player := league.ESPNLeague.Players[action.PlayerID]
-			actionStrings = append(actionStrings, fmt.Sprintf("%s %s %s (%s, %s)", league.ESPNLeague.Teams[action.TeamID].Name, action.Action, player.FullName, player.Position, player.Team))
-		}
-		fields = append(fields, &discordgo.MessageEmbedField{
-			Name:  time.UnixMilli(ra.Timestamp).String(),
-			Value: strings.Join(actionStrings, "\n"),
-		})
-	}
-	log.Printf("got recent activity: %v", recentActivity)
-	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-		Type: discordgo.InteractionResponseChannelMessageWithSource,
-		Data: &discordgo.InteractionResponseData{
-			Embeds: []*discordgo.MessageEmbed{
-				{
-					Fields: fields,
-				},
-			},
+
+	embeds := []*discordgo.MessageEmbed{
+		{
+			Title: fmt.Sprintf("Week %d charts", week),
+			URL:   chartURL(league, week, "index.html"),
 		},
-	})
-}
+	}
+	files := make([]*discordgo.File, 0)
 
-func handleChartsCommand(s *discordgo.Session, i *discordgo.InteractionCreate, league *config.LeagueClient, channel *discordgo.Channel) {
-	var week int
-	var season string
-	var id string
-	if league.LeagueType == config.LeagueTypeESPN {
-		week = league.ESPNLeague.CurrentWeek
-		season = fmt.Sprintf("%d", league.ESPNLeague.Year)
-		id = league.ESPNLeague.ID
-	} else if league.LeagueType == config.LeagueTypeSleeper {
-		status, err := league.SleeperLeague.Client.GetNflStatus()
-		if err != nil {
-			log.Printf("error getting sleeper status: %s\n", err)
-			s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-				Type: discordgo.InteractionResponseChannelMessageWithSource,
-				Data: &discordgo.InteractionResponseData{
-					Content: "could not get Sleeper league status",
-				},
-			})
-			return
+	matchups, err := league.Matchups(week)
+	if err != nil {
+		log.Printf("error getting matchups: %s\n", err)
+	}
+	projections, err := db.Projections(context.Background(), league, week)
+	if err != nil {
+		log.Printf("error getting projections: %s\n", err)
+	}
+	teams := league.Teams()
+	for _, m := range matchups {
+		if len(embeds) == maxEmbeds {
+			break
+		}
+		chart := matchupChart(league, teams, m, projections)
+		if chart == nil {
+			continue
 		}
-		week = status.Week
-		season = league.SleeperLeague.Season
-		id = league.SleeperLeague.ID
+		embeds = append(embeds, &discordgo.MessageEmbed{
+			Title: fmt.Sprintf("%s vs %s", teams[m.HomeTeamID].Name, teams[m.AwayTeamID].Name),
+			URL:   chartURL(league, week, fmt.Sprintf("%d.html", m.ID)),
+			Image: &discordgo.MessageEmbedImage{URL: "attachment://" + chart.Name},
+		})
+		files = append(files, chart)
 	}
 
-	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-		Type: discordgo.InteractionResponseChannelMessageWithSource,
-		Data: &discordgo.InteractionResponseData{
-			Embeds: []*discordgo.MessageEmbed{
-				{
-					Title: fmt.Sprintf("Week %d charts", week),
-					URL:   fmt.Sprintf("https://storage.googleapis.com/%s/%s/%s/%d/index.html", os.Getenv("PROJECTION_BUCKET"), id, season, week),
-				},
-			},
-		},
+	respond(s, i, &discordgo.InteractionResponseData{
+		Embeds: embeds,
+		Files:  files,
 	})
 }
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/craigatron/football-gobot/config"
+	"github.com/craigatron/football-gobot/store"
+)
+
+// maxAutocompleteChoices is the most choices Discord accepts in an
+// autocomplete response.
+const maxAutocompleteChoices = 25
+
+// maxEmbeds is the most embeds Discord allows in a message.
+const maxEmbeds = 10
+
+// chartURL is the URL of a page written by update-scores for the given week,
+// e.g. "index.html" or "<matchup ID>.html", or "" if pages aren't served.
+func chartURL(league config.League, week int, page string) string {
+	return currentState().config.OutputConfig.PublicURL(config.ChartPath(league, week, page))
+}
+
+// handleTeamAutocomplete suggests teams whose names contain what's been typed
+// so far in the focused option.
+func handleTeamAutocomplete(s *discordgo.Session, i *discordgo.InteractionCreate, league config.League) {
+	typed := ""
+	for _, o := range i.ApplicationCommandData().Options {
+		if o.Focused {
+			typed = strings.ToLower(o.StringValue())
+		}
+	}
+
+	teams := make([]config.Team, 0)
+	for _, t := range league.Teams() {
+		if strings.Contains(strings.ToLower(t.Name), typed) {
+			teams = append(teams, t)
+		}
+	}
+	sort.Slice(teams, func(i, j int) bool {
+		return teams[i].Name < teams[j].Name
+	})
+
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, 0, len(teams))
+	for _, t := range teams {
+		if len(choices) == maxAutocompleteChoices {
+			break
+		}
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+			Name:  t.Name,
+			Value: strconv.FormatInt(t.ID, 10),
+		})
+	}
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionApplicationCommandAutocompleteResult,
+		Data: &discordgo.InteractionResponseData{
+			Choices: choices,
+		},
+	})
+	if err != nil {
+		log.Printf("error responding to autocomplete: %s", err)
+	}
+}
+
+// teamMatchup finds the current week's matchup for the team picked in the
+// "team" option, or the caller's linked team.  If there isn't one, it responds
+// to the interaction saying why and returns false.
+func teamMatchup(s *discordgo.Session, i *discordgo.InteractionCreate, league config.League) (int, config.Matchup, bool) {
+	var teamID int64
+	hasTeam := false
+	for _, o := range i.ApplicationCommandData().Options {
+		if o.Name == "team" {
+			teamID, _ = strconv.ParseInt(o.StringValue(), 10, 64)
+			hasTeam = true
+		}
+	}
+	if !hasTeam {
+		if team, ok := linkedTeam(league, interactionUser(i).ID); ok {
+			teamID = team.ID
+		}
+	}
+	teams := league.Teams()
+	if _, ok := teams[teamID]; !ok {
+		content := "pick a team from the list"
+		if !hasTeam {
+			content = "pick a team, or use /link to make yours the default"
+		}
+		respond(s, i, &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		})
+		return 0, config.Matchup{}, false
+	}
+
+	week, err := league.CurrentWeek()
+	if err != nil {
+		respondError(s, i, fmt.Sprintf("could not get %s league status", league.Type()), err)
+		return 0, config.Matchup{}, false
+	}
+
+	matchups, err := league.Matchups(week)
+	if err != nil {
+		respondError(s, i, fmt.Sprintf("could not get week %d matchups", week), err)
+		return 0, config.Matchup{}, false
+	}
+	for _, m := range matchups {
+		if m.HomeTeamID == teamID || m.AwayTeamID == teamID {
+			return week, m, true
+		}
+	}
+	respondContent(s, i, fmt.Sprintf("%s doesn't have a matchup in week %d", teams[teamID].Name, week))
+	return 0, config.Matchup{}, false
+}
+
+func handleMatchupCommand(s *discordgo.Session, i *discordgo.InteractionCreate, league config.League, channel *discordgo.Channel) {
+	week, matchup, ok := teamMatchup(s, i, league)
+	if !ok {
+		return
+	}
+	teams := league.Teams()
+
+	lineups, err := league.Lineups(week)
+	if err != nil {
+		respondError(s, i, fmt.Sprintf("could not get week %d lineups", week), err)
+		return
+	}
+
+	mentions := teamMentions(league)
+	content := ""
+	if mentions[matchup.HomeTeamID] != "" && mentions[matchup.AwayTeamID] != "" {
+		content = fmt.Sprintf("%s vs %s", mentions[matchup.HomeTeamID], mentions[matchup.AwayTeamID])
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title: fmt.Sprintf("Week %d: %s vs %s", week, teams[matchup.HomeTeamID].Name, teams[matchup.AwayTeamID].Name),
+		URL:   chartURL(league, week, fmt.Sprintf("%d.html", matchup.ID)),
+		Fields: []*discordgo.MessageEmbedField{
+			lineupField(league, teams[matchup.HomeTeamID], matchup.HomeScore, lineups[matchup.HomeTeamID]),
+			lineupField(league, teams[matchup.AwayTeamID], matchup.AwayScore, lineups[matchup.AwayTeamID]),
+		},
+	}
+	files := make([]*discordgo.File, 0)
+	projections, err := db.Projections(context.Background(), league, week)
+	if err != nil {
+		log.Printf("error getting projections: %s\n", err)
+	} else {
+		if chart := matchupChart(league, teams, matchup, projections); chart != nil {
+			embed.Image = &discordgo.MessageEmbedImage{URL: "attachment://" + chart.Name}
+			files = append(files, chart)
+		}
+		if home, away, ok := latestWinProbabilities(projections, matchup); ok {
+			embed.Description = fmt.Sprintf("Win probability: %s %.0f%%, %s %.0f%%",
+				teams[matchup.HomeTeamID].Name, home*100, teams[matchup.AwayTeamID].Name, away*100)
+		}
+	}
+
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: content,
+		// show who's playing without pinging them
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+		Embeds:          []*discordgo.MessageEmbed{embed},
+		Files:           files,
+	})
+}
+
+// lineupField lists a team's starters with actual and projected points, to be
+// shown inline next to their opponent's.
+func lineupField(league config.League, team config.Team, score float64, lineup config.Lineup) *discordgo.MessageEmbedField {
+	lines := make([]string, 0, len(lineup.Players))
+	projected := 0.0
+	for _, p := range lineup.Players {
+		if !p.Starter {
+			continue
+		}
+		name := p.PlayerID
+		if player, ok := league.Player(p.PlayerID); ok {
+			name = player.FullName
+		}
+		lines = append(lines, fmt.Sprintf("`%-5s` %s **%.1f** (%.1f)", p.Slot, name, p.Points, p.Projected))
+		projected += p.Projected
+	}
+	if len(lines) == 0 {
+		lines = append(lines, "no starters")
+	}
+	return &discordgo.MessageEmbedField{
+		Name:   fmt.Sprintf("%s: %.2f (proj %.2f)", team.Name, score, projected),
+		Value:  strings.Join(lines, "\n"),
+		Inline: true,
+	}
+}
+
+// latestWinProbabilities returns each team's most recent chance of winning
+// the matchup, if it's been tracked.
+func latestWinProbabilities(projections []store.Projection, matchup config.Matchup) (float64, float64, bool) {
+	home := teamProjections(projections, matchup.HomeTeamID)
+	away := teamProjections(projections, matchup.AwayTeamID)
+	if len(home) == 0 || len(away) == 0 {
+		return 0, 0, false
+	}
+	h, a := home[len(home)-1], away[len(away)-1]
+	if h.Timestamp != a.Timestamp || h.WinProbability+a.WinProbability == 0 {
+		return 0, 0, false
+	}
+	return h.WinProbability, a.WinProbability, true
+}
+
+func handleWinProbabilityCommand(s *discordgo.Session, i *discordgo.InteractionCreate, league config.League, channel *discordgo.Channel) {
+	week, matchup, ok := teamMatchup(s, i, league)
+	if !ok {
+		return
+	}
+	teams := league.Teams()
+
+	projections, err := db.Projections(context.Background(), league, week)
+	if err != nil {
+		respondError(s, i, fmt.Sprintf("could not get week %d projections", week), err)
+		return
+	}
+	chart := winProbabilityChart(league, teams, matchup, projections)
+	if chart == nil {
+		respondContent(s, i, fmt.Sprintf("no win probabilities for %s vs %s yet", teams[matchup.HomeTeamID].Name, teams[matchup.AwayTeamID].Name))
+		return
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title: fmt.Sprintf("Week %d: %s vs %s", week, teams[matchup.HomeTeamID].Name, teams[matchup.AwayTeamID].Name),
+		URL:   chartURL(league, week, fmt.Sprintf("%d.html", matchup.ID)),
+		Image: &discordgo.MessageEmbedImage{URL: "attachment://" + chart.Name},
+	}
+	if home, away, ok := latestWinProbabilities(projections, matchup); ok {
+		embed.Description = fmt.Sprintf("%s %.0f%%, %s %.0f%%",
+			teams[matchup.HomeTeamID].Name, home*100, teams[matchup.AwayTeamID].Name, away*100)
+	}
+	respond(s, i, &discordgo.InteractionResponseData{
+		Embeds: []*discordgo.MessageEmbed{embed},
+		Files:  []*discordgo.File{chart},
+	})
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/craigatron/football-gobot/config"
+	"github.com/craigatron/football-gobot/store"
+)
+
+// playerRecentWeeks is how many of the latest weeks /player looks for the
+// player on a roster in.
+const playerRecentWeeks = 3
+
+// playerTransactions is how many of a player's latest transactions /player
+// lists.
+const playerTransactions = 10
+
+// playerAutocompleteWait is how long autocomplete waits for a league's player
+// database to load before suggesting no one.  Discord gives up on
+// autocomplete after 3 seconds.
+const playerAutocompleteWait = 2 * time.Second
+
+// playerLabel describes a player with their position and NFL team, e.g.
+// "Player Name (RB, SEA)".
+func playerLabel(p config.Player) string {
+	if p.NFLTeam == "" {
+		return fmt.Sprintf("%s (%s)", p.FullName, p.Position)
+	}
+	return fmt.Sprintf("%s (%s, %s)", p.FullName, p.Position, p.NFLTeam)
+}
+
+// handlePlayerAutocomplete suggests players whose names contain what's been
+// typed so far, those whose names start with it first.  While the league's
+// player database is still loading it suggests no one.
+func handlePlayerAutocomplete(s *discordgo.Session, i *discordgo.InteractionCreate, league config.League) {
+	typed := strings.ToLower(optionValue(i, "name"))
+	players := make([]config.Player, 0)
+	if typed != "" {
+		loaded := make(chan []config.Player, 1)
+		go func() {
+			loaded <- league.Players()
+		}()
+		select {
+		case all := <-loaded:
+			for _, p := range all {
+				if strings.Contains(strings.ToLower(p.FullName), typed) {
+					players = append(players, p)
+				}
+			}
+		case <-time.After(playerAutocompleteWait):
+			log.Printf("players for %s league %s still loading, suggesting none", league.Type(), league.ID())
+		}
+	}
+	sort.Slice(players, func(i, j int) bool {
+		iPrefix := strings.HasPrefix(strings.ToLower(players[i].FullName), typed)
+		jPrefix := strings.HasPrefix(strings.ToLower(players[j].FullName), typed)
+		if iPrefix != jPrefix {
+			return iPrefix
+		}
+		// players on an NFL team are more likely to be who's wanted
+		if (players[i].NFLTeam == "") != (players[j].NFLTeam == "") {
+			return players[i].NFLTeam != ""
+		}
+		return players[i].FullName < players[j].FullName
+	})
+
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, 0, maxAutocompleteChoices)
+	for _, p := range players {
+		if len(choices) == maxAutocompleteChoices {
+			break
+		}
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+			Name:  playerLabel(p),
+			Value: p.ID,
+		})
+	}
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionApplicationCommandAutocompleteResult,
+		Data: &discordgo.InteractionResponseData{
+			Choices: choices,
+		},
+	})
+	if err != nil {
+		log.Printf("error responding to autocomplete: %s", err)
+	}
+}
+
+// findPlayer looks up the player picked in the name option, or typed without
+// picking one if exactly one player has that name.
+func findPlayer(league config.League, value string) (config.Player, bool) {
+	if p, ok := league.Player(value); ok {
+		return p, true
+	}
+	var found config.Player
+	matches := 0
+	for _, p := range league.Players() {
+		if strings.EqualFold(p.FullName, value) {
+			found = p
+			matches++
+		}
+	}
+	if matches != 1 {
+		return config.Player{}, false
+	}
+	return league.Player(found.ID)
+}
+
+// playerWeek is a week a player spent on a fantasy roster.
+type playerWeek struct {
+	week    int
+	teamID  int64
+	points  float64
+	starter bool
+}
+
+// lineupsKey identifies a week's lineups in closedLineups.
+type lineupsKey struct {
+	leagueYear string
+	week       int
+}
+
+// closedLineups caches the lineups of closed weeks by lineupsKey, keyed by
+// config.LeagueYearKey.  They don't change once a week is closed, so /player
+// only fetches the weeks still being played.
+var closedLineups sync.Map
+
+// weekLineups returns a week's lineups, cached if the week is closed.
+func weekLineups(league config.League, week int, closedWeek int) (map[int64]config.Lineup, error) {
+	key := lineupsKey{config.LeagueYearKey(league), week}
+	if lineups, ok := closedLineups.Load(key); ok {
+		return lineups.(map[int64]config.Lineup), nil
+	}
+	lineups, err := league.Lineups(week)
+	if err != nil {
+		return nil, err
+	}
+	if week <= closedWeek {
+		closedLineups.Store(key, lineups)
+	}
+	return lineups, nil
+}
+
+// playerWeeks finds the player in the lineups of the playerRecentWeeks weeks
+// through the given week.
+func playerWeeks(league config.League, id string, through int, closedWeek int) ([]playerWeek, error) {
+	weeks := make([]playerWeek, 0)
+	first := through - playerRecentWeeks + 1
+	if first < 1 {
+		first = 1
+	}
+	for week := first; week <= through; week++ {
+		lineups, err := weekLineups(league, week, closedWeek)
+		if err != nil {
+			return nil, err
+		}
+		for teamID, lineup := range lineups {
+			for _, p := range lineup.Players {
+				if p.PlayerID == id {
+					weeks = append(weeks, playerWeek{week, teamID, p.Points, p.Starter})
+				}
+			}
+		}
+	}
+	return weeks, nil
+}
+
+func handlePlayerCommand(s *discordgo.Session, i *discordgo.InteractionCreate, league config.League, channel *discordgo.Channel) {
+	ctx := context.Background()
+	player, ok := findPlayer(league, optionValue(i, "name"))
+	if !ok {
+		respondPrivately(s, i, "pick a player from the list")
+		return
+	}
+	teams := league.Teams()
+
+	status := "Healthy"
+	if player.InjuryStatus != "" {
+		status = player.InjuryStatus
+	}
+	rosteredBy := "Free agent"
+	rosters, err := league.Rosters()
+	if err != nil {
+		respondError(s, i, "could not get rosters for league", err)
+		return
+	}
+	for teamID, roster := range rosters {
+		for _, id := range roster.PlayerIDs {
+			if id == player.ID {
+				rosteredBy = teams[teamID].Name
+			}
+		}
+	}
+	points, err := league.SeasonPoints(player.ID)
+	if err != nil {
+		respondError(s, i, "could not get the player's stats", err)
+		return
+	}
+	fields := []*discordgo.MessageEmbedField{
+		{Name: "Status", Value: status, Inline: true},
+		{Name: "Rostered by", Value: rosteredBy, Inline: true},
+		{Name: "Season points", Value: fmt.Sprintf("%.2f", points), Inline: true},
+	}
+
+	currentWeek, err := league.CurrentWeek()
+	if err != nil {
+		respondError(s, i, fmt.Sprintf("could not get %s league status", league.Type()), err)
+		return
+	}
+	leagueYear, err := db.LeagueYear(ctx, league)
+	if err != nil {
+		respondError(s, i, "could not get league status", err)
+		return
+	}
+	weeks, err := playerWeeks(league, player.ID, currentWeek, leagueYear.ClosedWeek)
+	if err != nil {
+		respondError(s, i, "could not get lineups for league", err)
+		return
+	}
+	if len(weeks) > 0 {
+		lines := make([]string, 0, playerRecentWeeks)
+		for j := len(weeks) - 1; j >= 0; j-- {
+			w := weeks[j]
+			role := "benched"
+			if w.starter {
+				role = "started"
+			}
+			lines = append(lines, fmt.Sprintf("Week %d: %.2f, %s by %s", w.week, w.points, role, teams[w.teamID].Name))
+		}
+		fields = append(fields, &discordgo.MessageEmbedField{Name: "Recent weeks", Value: strings.Join(lines, "\n")})
+	}
+
+	loc, err := league.Config().Location()
+	if err != nil {
+		respondError(s, i, "could not get the league's timezone", err)
+		return
+	}
+	activity, err := db.QueryActivity(ctx, league, store.ActivityQuery{
+		Match: func(a config.Activity) bool {
+			for _, action := range a.Actions {
+				if action.PlayerID == player.ID {
+					return true
+				}
+			}
+			return false
+		},
+		Limit: playerTransactions,
+	})
+	if err != nil {
+		respondError(s, i, "could not get activity for league", err)
+		return
+	}
+	lines := make([]string, 0, playerTransactions)
+	for _, a := range activity {
+		for _, action := range a.Actions {
+			if action.PlayerID != player.ID {
+				continue
+			}
+			date := time.UnixMilli(a.Timestamp).In(loc).Format("Jan 2")
+			lines = append(lines, fmt.Sprintf("%s: %s", date, config.FormatAction(league, teams, action)))
+		}
+	}
+	if len(lines) > playerTransactions {
+		lines = lines[:playerTransactions]
+	}
+	if len(lines) == 0 {
+		lines = append(lines, "none this season")
+	}
+	fields = append(fields, &discordgo.MessageEmbedField{Name: "Transactions", Value: strings.Join(lines, "\n")})
+
+	respond(s, i, &discordgo.InteractionResponseData{
+		Embeds: []*discordgo.MessageEmbed{
+			{
+				Title:  playerLabel(player),
+				Fields: fields,
+			},
+		},
+	})
+}
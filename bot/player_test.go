@@ -0,0 +1,64 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/craigatron/football-gobot/config"
+	"github.com/craigatron/football-gobot/config/configtest"
+)
+
+// lineupsLeague serves a player's weeks on two teams and counts the lineups
+// fetched.
+type lineupsLeague struct {
+	configtest.League
+	fetched map[int]int
+}
+
+func (l lineupsLeague) Season() string {
+	return "2021"
+}
+
+func (l lineupsLeague) Lineups(week int) (map[int64]config.Lineup, error) {
+	l.fetched[week]++
+	teamID := int64(1)
+	if week > 2 {
+		teamID = 2
+	}
+	return map[int64]config.Lineup{
+		teamID: {TeamID: teamID, Players: []config.LineupPlayer{{PlayerID: "7", Starter: week%2 == 1, Points: float64(week)}}},
+		3:      {TeamID: 3, Players: []config.LineupPlayer{{PlayerID: "8", Starter: true, Points: 20}}},
+	}, nil
+}
+
+func TestPlayerWeeks(t *testing.T) {
+	league := lineupsLeague{fetched: make(map[int]int)}
+	// only the last playerRecentWeeks weeks are looked at
+	want := []playerWeek{
+		{week: 3, teamID: 2, points: 3, starter: true},
+		{week: 4, teamID: 2, points: 4},
+		{week: 5, teamID: 2, points: 5, starter: true},
+	}
+	for run := 0; run < 2; run++ {
+		got, err := playerWeeks(league, "7", 5, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("playerWeeks() = %+v, want %+v", got, want)
+		}
+	}
+	// closed weeks are only fetched once
+	wantFetched := map[int]int{3: 1, 4: 2, 5: 2}
+	if !reflect.DeepEqual(league.fetched, wantFetched) {
+		t.Errorf("lineups fetched = %v, want %v", league.fetched, wantFetched)
+	}
+
+	early, err := playerWeeks(league, "7", 2, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(early) != 2 || early[0].week != 1 {
+		t.Errorf("playerWeeks() through week 2 = %+v, want weeks 1 and 2", early)
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/craigatron/football-gobot/config"
+)
+
+// weeklyScores returns each team's final score in every week through the
+// given one, from the results saved when the weeks were closed.
+func weeklyScores(ctx context.Context, league config.League, through int) (config.WeeklyScores, error) {
+	weekly := make(config.WeeklyScores)
+	for week := 1; week <= through; week++ {
+		results, err := db.Results(ctx, league, week)
+		if err != nil {
+			return nil, err
+		}
+		weekly[week] = make(map[int64]float64)
+		for _, r := range results {
+			weekly[week][r.HomeTeamID] = r.HomeScore
+			weekly[week][r.AwayTeamID] = r.AwayScore
+		}
+	}
+	return weekly, nil
+}
+
+// savedPowerRankings returns the league's power rankings after a closed week.
+// They're ranked with the league's weights the first time they're asked for
+// and saved, so movement doesn't change when the weights do.
+func savedPowerRankings(ctx context.Context, league config.League, week int, weekly config.WeeklyScores) ([]config.PowerRanking, error) {
+	rankings, err := db.PowerRankings(ctx, league, week)
+	if err != nil || len(rankings) > 0 {
+		return rankings, err
+	}
+	rankings = config.PowerRankings(league.Config().RankingWeights(), weekly, week)
+	if err := db.SetPowerRankings(ctx, league, week, rankings); err != nil {
+		return nil, err
+	}
+	return rankings, nil
+}
+
+// formatMovement describes how far a team moved since its previous rank, or
+// is empty if it wasn't ranked.
+func formatMovement(rank int, previous int) string {
+	switch {
+	case previous == 0:
+		return ""
+	case rank < previous:
+		return fmt.Sprintf("▲%d", previous-rank)
+	case rank > previous:
+		return fmt.Sprintf("▼%d", rank-previous)
+	}
+	return "-"
+}
+
+func handlePowerRankingsCommand(s *discordgo.Session, i *discordgo.InteractionCreate, league config.League, channel *discordgo.Channel) {
+	ctx := context.Background()
+	leagueYear, err := db.LeagueYear(ctx, league)
+	if err != nil {
+		respondError(s, i, "could not get league status", err)
+		return
+	}
+	week := leagueYear.ClosedWeek
+	formula := ""
+	for _, o := range i.ApplicationCommandData().Options {
+		switch o.Name {
+		case "week":
+			week = int(o.IntValue())
+		case "formula":
+			formula = o.StringValue()
+		}
+	}
+	if leagueYear.ClosedWeek == 0 {
+		respondContent(s, i, "no weeks have been closed out yet")
+		return
+	}
+	if week < 1 || week > leagueYear.ClosedWeek {
+		respondContent(s, i, fmt.Sprintf("week %d isn't over yet, pick a week from 1 to %d", week, leagueYear.ClosedWeek))
+		return
+	}
+	if _, ok := config.RankingFormulas[formula]; formula != "" && !ok {
+		respondContent(s, i, fmt.Sprintf("unknown formula %s, pick one of %s", formula, strings.Join(config.RankingFormulaNames(), ", ")))
+		return
+	}
+
+	weekly, err := weeklyScores(ctx, league, week)
+	if err != nil {
+		respondError(s, i, "could not get results for league", err)
+		return
+	}
+
+	// the league's own rankings are saved; rankings by a single formula are
+	// worked out on the fly
+	var rankings, previous []config.PowerRanking
+	if formula == "" {
+		rankings, err = savedPowerRankings(ctx, league, week, weekly)
+		if err == nil && week > 1 {
+			previous, err = savedPowerRankings(ctx, league, week-1, weekly)
+		}
+	} else {
+		weights := map[string]float64{formula: 1}
+		rankings = config.PowerRankings(weights, weekly, week)
+		if week > 1 {
+			previous = config.PowerRankings(weights, weekly, week-1)
+		}
+	}
+	if err != nil {
+		respondError(s, i, "could not get power rankings for league", err)
+		return
+	}
+
+	previousRanks := make(map[int64]int)
+	for _, r := range previous {
+		previousRanks[r.TeamID] = r.Rank
+	}
+	teams := league.Teams()
+	var sb strings.Builder
+	sb.WriteString("```\n")
+	fmt.Fprintf(&sb, "%-2s %-20s %5s %s\n", "#", "Team", "Score", "Move")
+	for _, r := range rankings {
+		fmt.Fprintf(&sb, "%-2d %-20s %5.2f %s\n",
+			r.Rank,
+			truncate(teams[r.TeamID].Name, 20),
+			r.Score,
+			formatMovement(r.Rank, previousRanks[r.TeamID]))
+	}
+	sb.WriteString("```")
+
+	title := fmt.Sprintf("%s week %d power rankings", league.Config().Name, week)
+	if formula != "" {
+		title = fmt.Sprintf("%s by %s", title, formula)
+	}
+	respond(s, i, &discordgo.InteractionResponseData{
+		Embeds: []*discordgo.MessageEmbed{
+			{
+				Title:       title,
+				Description: sb.String(),
+			},
+		},
+	})
+}
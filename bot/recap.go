@@ -0,0 +1,328 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/craigatron/football-gobot/config"
+	"github.com/craigatron/football-gobot/store"
+)
+
+// recapPickupWindow is how far back to look for pickups when the previous
+// week has no saved projections to mark where it ended.
+const recapPickupWindow = 7 * 24 * time.Hour
+
+// recapEmbed summarizes a closed week: the highest and lowest scorers, the
+// biggest blowout and closest game, the biggest comeback, the best bench
+// player and the top pickup.  Anything that can't be worked out from what's
+// saved is left out.
+func recapEmbed(ctx context.Context, league config.League, week int) (*discordgo.MessageEmbed, error) {
+	results, err := db.Results(ctx, league, week)
+	if err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return nil, fmt.Errorf("week %d hasn't been closed out", week)
+	}
+	teams := league.Teams()
+	fields := make([]*discordgo.MessageEmbedField, 0)
+	addField := func(name string, value string) {
+		fields = append(fields, &discordgo.MessageEmbedField{Name: name, Value: value})
+	}
+
+	type teamScore struct {
+		result store.Result
+		teamID int64
+		score  float64
+	}
+	scores := make([]teamScore, 0, 2*len(results))
+	blowout, closest := results[0], results[0]
+	for _, r := range results {
+		scores = append(scores, teamScore{r, r.HomeTeamID, r.HomeScore}, teamScore{r, r.AwayTeamID, r.AwayScore})
+		if margin(r) > margin(blowout) {
+			blowout = r
+		}
+		if margin(r) < margin(closest) {
+			closest = r
+		}
+	}
+	high, low := scores[0], scores[0]
+	for _, ts := range scores {
+		if ts.score > high.score {
+			high = ts
+		}
+		if ts.score < low.score {
+			low = ts
+		}
+	}
+	addField("Highest scorer", fmt.Sprintf("%s with %.2f against %s", teams[high.teamID].Name, high.score, teams[opponent(high.result, high.teamID)].Name))
+	addField("Lowest scorer", fmt.Sprintf("%s with %.2f against %s", teams[low.teamID].Name, low.score, teams[opponent(low.result, low.teamID)].Name))
+	addField("Biggest blowout", formatResult(teams, blowout))
+	addField("Closest game", formatResult(teams, closest))
+
+	projections, err := db.Projections(ctx, league, week)
+	if err != nil {
+		log.Printf("error getting week %d projections: %s", week, err)
+		projections = nil
+	} else if comeback := biggestComeback(teams, results, projections); comeback != "" {
+		addField("Biggest comeback", comeback)
+	}
+
+	lineups, err := league.Lineups(week)
+	if err != nil {
+		log.Printf("error getting week %d lineups: %s", week, err)
+	} else {
+		if bench := bestBenchPlayer(league, teams, lineups); bench != "" {
+			addField("Best player left on the bench", bench)
+		}
+		if pickup, err := topPickup(ctx, league, teams, week, projections, lineups); err != nil {
+			log.Printf("error finding week %d pickups: %s", week, err)
+		} else if pickup != "" {
+			addField("Top pickup", pickup)
+		}
+	}
+
+	return &discordgo.MessageEmbed{
+		Title:  fmt.Sprintf("%s week %d recap", league.Config().Name, week),
+		URL:    chartURL(league, week, "index.html"),
+		Fields: fields,
+	}, nil
+}
+
+func margin(r store.Result) float64 {
+	return math.Abs(r.HomeScore - r.AwayScore)
+}
+
+func opponent(r store.Result, teamID int64) int64 {
+	if r.HomeTeamID == teamID {
+		return r.AwayTeamID
+	}
+	return r.HomeTeamID
+}
+
+// formatResult describes a result winner first, e.g. "A beat B 120.00 - 80.00".
+func formatResult(teams map[int64]config.Team, r store.Result) string {
+	if r.WinnerID == 0 {
+		return fmt.Sprintf("%s and %s tied at %.2f", teams[r.HomeTeamID].Name, teams[r.AwayTeamID].Name, r.HomeScore)
+	}
+	loserID := opponent(r, r.WinnerID)
+	winnerScore, loserScore := r.HomeScore, r.AwayScore
+	if r.WinnerID == r.AwayTeamID {
+		winnerScore, loserScore = loserScore, winnerScore
+	}
+	return fmt.Sprintf("%s beat %s %.2f - %.2f", teams[r.WinnerID].Name, teams[loserID].Name, winnerScore, loserScore)
+}
+
+// biggestComeback finds the winner who looked most likely to lose at some
+// point in the week, by their lowest win probability or, for weeks saved
+// before those were tracked, their biggest projected deficit.
+func biggestComeback(teams map[int64]config.Team, results []store.Result, projections []store.Projection) string {
+	var best store.Result
+	lowestWinProb := 1.0
+	biggestDeficit := 0.0
+	for _, r := range results {
+		if r.WinnerID == 0 {
+			continue
+		}
+		winner := teamProjections(projections, r.WinnerID)
+		loser := teamProjections(projections, opponent(r, r.WinnerID))
+		loserAt := make(map[int64]store.Projection)
+		for _, p := range loser {
+			loserAt[p.Timestamp] = p
+		}
+		for _, p := range winner {
+			l, ok := loserAt[p.Timestamp]
+			if !ok {
+				continue
+			}
+			if p.WinProbability+l.WinProbability > 0 {
+				if p.WinProbability < lowestWinProb {
+					best, lowestWinProb = r, p.WinProbability
+				}
+			} else if lowestWinProb == 1 && l.Projection-p.Projection > biggestDeficit {
+				best, biggestDeficit = r, l.Projection-p.Projection
+			}
+		}
+	}
+
+	switch {
+	case lowestWinProb < 0.5:
+		return fmt.Sprintf("%s came back from a %.0f%% chance to win: %s", teams[best.WinnerID].Name, lowestWinProb*100, formatResult(teams, best))
+	case lowestWinProb == 1 && biggestDeficit > 0:
+		return fmt.Sprintf("%s came back from %.2f points down in the projections: %s", teams[best.WinnerID].Name, biggestDeficit, formatResult(teams, best))
+	}
+	return ""
+}
+
+// bestBenchPlayer finds the bench player who scored the most points.
+func bestBenchPlayer(league config.League, teams map[int64]config.Team, lineups map[int64]config.Lineup) string {
+	var best config.LineupPlayer
+	var bestTeam int64
+	found := false
+	for teamID, lineup := range lineups {
+		for _, p := range lineup.Players {
+			if p.Starter || (found && p.Points <= best.Points) {
+				continue
+			}
+			best, bestTeam, found = p, teamID, true
+		}
+	}
+	if !found || best.Points <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("%s scored %.2f on %s's bench", playerName(league, best.PlayerID), best.Points, teams[bestTeam].Name)
+}
+
+// isPickup is whether an action adds a player to a team, from free agency or
+// waivers.  It uses the /activity filters, since ESPN names its actions
+// differently from Sleeper's.
+func isPickup(action string) bool {
+	if activityActionTypes["drop"](action) {
+		return false
+	}
+	return activityActionTypes["add"](action) || activityActionTypes["waiver"](action)
+}
+
+// topPickup finds the player added during the week who scored the most for
+// the team that added them.  The week runs from the last projection saved for
+// the previous week to the last one saved for this week.
+func topPickup(ctx context.Context, league config.League, teams map[int64]config.Team, week int, projections []store.Projection, lineups map[int64]config.Lineup) (string, error) {
+	if len(projections) == 0 {
+		return "", nil
+	}
+	end := projections[len(projections)-1].Timestamp
+	start := end - recapPickupWindow.Milliseconds()
+	if week > 1 {
+		prev, err := db.Projections(ctx, league, week-1)
+		if err != nil {
+			return "", err
+		}
+		if len(prev) > 0 {
+			start = prev[len(prev)-1].Timestamp
+		}
+	}
+	activity, err := db.ActivitySince(ctx, league, start)
+	if err != nil {
+		return "", err
+	}
+
+	points := make(map[int64]map[string]config.LineupPlayer)
+	for teamID, lineup := range lineups {
+		points[teamID] = make(map[string]config.LineupPlayer)
+		for _, p := range lineup.Players {
+			points[teamID][p.PlayerID] = p
+		}
+	}
+
+	var best config.LineupPlayer
+	var bestAction config.ActivityAction
+	found := false
+	for _, a := range activity {
+		if a.Timestamp > end {
+			break
+		}
+		for _, action := range a.Actions {
+			if !isPickup(action.Action) {
+				continue
+			}
+			p, ok := points[action.TeamID][action.PlayerID]
+			if !ok || (found && p.Points <= best.Points) {
+				continue
+			}
+			best, bestAction, found = p, action, true
+		}
+	}
+	if !found {
+		return "", nil
+	}
+	started := "on the bench"
+	if best.Starter {
+		started = "as a starter"
+	}
+	return fmt.Sprintf("%s picked up %s, who scored %.2f %s", teams[bestAction.TeamID].Name, playerName(league, best.PlayerID), best.Points, started), nil
+}
+
+func playerName(league config.League, id string) string {
+	if player, ok := league.Player(id); ok {
+		return player.FullName
+	}
+	return fmt.Sprintf("player %s", id)
+}
+
+// postRecaps posts the latest closed week's recap to each league's
+// bot_update_channels, unless it's already been posted.
+func postRecaps(ctx context.Context, s *discordgo.Session) error {
+	failed := 0
+	leagues := currentState().leagues()
+	for _, league := range leagues {
+		if len(league.Config().BotUpdateChannels) == 0 {
+			continue
+		}
+		if err := postLeagueRecap(ctx, s, league); err != nil {
+			log.Printf("error posting recap for %s league %s: %s", league.Type(), league.ID(), err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d leagues failed", failed, len(leagues))
+	}
+	return nil
+}
+
+func postLeagueRecap(ctx context.Context, s *discordgo.Session, league config.League) error {
+	leagueYear, err := db.LeagueYear(ctx, league)
+	if err != nil {
+		return err
+	}
+	week := leagueYear.ClosedWeek
+	if week == 0 || week <= leagueYear.RecapNotified {
+		return nil
+	}
+
+	embed, err := recapEmbed(ctx, league, week)
+	if err != nil {
+		return err
+	}
+	for _, c := range league.Config().BotUpdateChannels {
+		if _, err := s.ChannelMessageSendEmbed(c, embed); err != nil {
+			log.Printf("error posting week %d recap to channel %s: %s", week, c, err)
+		}
+	}
+	return db.UpdateLeagueYear(ctx, league, store.LeagueYear{RecapNotified: week})
+}
+
+func handleRecapCommand(s *discordgo.Session, i *discordgo.InteractionCreate, league config.League, channel *discordgo.Channel) {
+	ctx := context.Background()
+	leagueYear, err := db.LeagueYear(ctx, league)
+	if err != nil {
+		respondError(s, i, "could not get league status", err)
+		return
+	}
+	week := leagueYear.ClosedWeek
+	for _, o := range i.ApplicationCommandData().Options {
+		if o.Name == "week" {
+			week = int(o.IntValue())
+		}
+	}
+	if leagueYear.ClosedWeek == 0 {
+		respondContent(s, i, "no weeks have been closed out yet")
+		return
+	}
+	if week < 1 || week > leagueYear.ClosedWeek {
+		respondContent(s, i, fmt.Sprintf("week %d isn't over yet, pick a week from 1 to %d", week, leagueYear.ClosedWeek))
+		return
+	}
+
+	embed, err := recapEmbed(ctx, league, week)
+	if err != nil {
+		respondError(s, i, fmt.Sprintf("could not build week %d recap", week), err)
+		return
+	}
+	respond(s, i, &discordgo.InteractionResponseData{
+		Embeds: []*discordgo.MessageEmbed{embed},
+	})
+}
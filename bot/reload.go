@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/craigatron/football-gobot/config"
+)
+
+const defaultConfigReloadInterval = time.Minute
+
+// botState is everything derived from the config.  It's never modified once
+// stored, so handlers can keep using a snapshot while a reload swaps in a new
+// one.
+type botState struct {
+	config            *config.JSON
+	leaguesByCategory map[string]config.League
+	// leaguesByKey maps config.LeagueKey to each league, for the league
+	// option and server default leagues.
+	leaguesByKey map[string]config.League
+}
+
+// state holds the current *botState.
+var state atomic.Value
+
+// currentState is the latest config snapshot.  Handlers should call this once
+// and use the result throughout.
+func currentState() *botState {
+	return state.Load().(*botState)
+}
+
+// newBotState creates the leagues in c.  Leagues whose config hasn't changed
+// since prev are reused rather than fetched again.
+func newBotState(c *config.JSON, prev *botState) (*botState, error) {
+	prevLeagues := make(map[string]config.League)
+	if prev != nil && prev.config.ESPNConfig == c.ESPNConfig && prev.config.SleeperConfig == c.SleeperConfig {
+		for _, l := range prev.leagues() {
+			prevLeagues[leagueConfigKey(l.Config())] = l
+		}
+	}
+
+	st := &botState{
+		config:            c,
+		leaguesByCategory: make(map[string]config.League),
+		leaguesByKey:      make(map[string]config.League),
+	}
+	for _, lc := range c.LeagueConfig {
+		league, ok := prevLeagues[leagueConfigKey(lc)]
+		if !ok || !reflect.DeepEqual(league.Config(), lc) {
+			var err error
+			league, err = config.NewLeague(*c, lc)
+			if err != nil {
+				return nil, fmt.Errorf("creating %s league %s: %w", lc.LeagueType, lc.ID, err)
+			}
+			// load the player database now rather than on the first
+			// /player autocomplete, which can't wait for it
+			go league.Players()
+		}
+		st.leaguesByKey[config.LeagueKey(league)] = league
+		for _, d := range lc.DiscordCategoryIDs {
+			st.leaguesByCategory[d] = league
+		}
+	}
+	return st, nil
+}
+
+func leagueConfigKey(l config.LeagueConfigJSON) string {
+	return l.LeagueType + "-" + l.ID
+}
+
+// leagues returns each league in the state.
+func (st *botState) leagues() []config.League {
+	leagues := make([]config.League, 0, len(st.leaguesByKey))
+	for _, l := range st.leaguesByKey {
+		leagues = append(leagues, l)
+	}
+	return leagues
+}
+
+// configReloadInterval is how often to check the config source for changes,
+// from CONFIG_RELOAD_INTERVAL.  Zero disables reloading.
+func configReloadInterval() time.Duration {
+	v := os.Getenv("CONFIG_RELOAD_INTERVAL")
+	if v == "" {
+		return defaultConfigReloadInterval
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		log.Printf("invalid CONFIG_RELOAD_INTERVAL %q, using %s: %s", v, defaultConfigReloadInterval, err)
+		return defaultConfigReloadInterval
+	}
+	return d
+}
+
+// watchConfig polls src and swaps in the new config whenever its version
+// changes.  An invalid config is logged and ignored, keeping the current one.
+func watchConfig(s *discordgo.Session, src config.Source, version string, interval time.Duration) {
+	for range time.Tick(interval) {
+		ctx := context.Background()
+		v, err := src.Version(ctx)
+		if err != nil {
+			log.Printf("error checking config version: %s", err)
+			continue
+		}
+		if v == version {
+			continue
+		}
+
+		log.Printf("config %s changed, reloading", src)
+		c, err := config.LoadBotConfigFrom(ctx, src)
+		if err != nil {
+			log.Printf("not reloading config: %s", err)
+			continue
+		}
+		prev := currentState()
+		st, err := newBotState(&c, prev)
+		if err != nil {
+			log.Printf("not reloading config: %s", err)
+			continue
+		}
+		state.Store(st)
+		version = v
+
+		changes := configChanges(prev.config, st.config)
+		log.Printf("reloaded config: %s", strings.Join(changes, "; "))
+		if c.AdminChannel != "" {
+			_, err := s.ChannelMessageSendEmbed(c.AdminChannel, &discordgo.MessageEmbed{
+				Title:       "Config reloaded",
+				Description: "- " + strings.Join(changes, "\n- "),
+			})
+			if err != nil {
+				log.Printf("error posting config notice: %s", err)
+			}
+		}
+	}
+}
+
+// configChanges summarizes the differences between two configs.
+func configChanges(prev *config.JSON, next *config.JSON) []string {
+	changes := make([]string, 0)
+	if prev.AppID != next.AppID || prev.Token != next.Token {
+		changes = append(changes, "Discord app ID or token changed, restart required to take effect")
+	}
+	if !reflect.DeepEqual(prev.CommandGuildIDs, next.CommandGuildIDs) {
+		changes = append(changes, "command guilds changed, restart required to take effect")
+	}
+	if !reflect.DeepEqual(prev.ReaccConfig, next.ReaccConfig) {
+		changes = append(changes, fmt.Sprintf("reaccs: %d -> %d, ignored reaccs: %d -> %d",
+			len(prev.ReaccConfig.Reaccs), len(next.ReaccConfig.Reaccs),
+			len(prev.ReaccConfig.IgnoreReaccs), len(next.ReaccConfig.IgnoreReaccs)))
+	}
+	if prev.StorageConfig != next.StorageConfig {
+		changes = append(changes, "storage config changed, restart required to take effect")
+	}
+	if !reflect.DeepEqual(prev.JobsConfig, next.JobsConfig) {
+		changes = append(changes, "job schedules changed, restart required to take effect")
+	}
+	if prev.OutputConfig != next.OutputConfig {
+		changes = append(changes, "output config changed, restart required for update-scores to write to it")
+	}
+	if prev.ESPNConfig != next.ESPNConfig {
+		changes = append(changes, "ESPN config changed")
+	}
+	if prev.SleeperConfig != next.SleeperConfig {
+		changes = append(changes, "Sleeper config changed")
+	}
+
+	prevLeagues := make(map[string]config.LeagueConfigJSON)
+	for _, l := range prev.LeagueConfig {
+		prevLeagues[leagueConfigKey(l)] = l
+	}
+	nextLeagues := make(map[string]config.LeagueConfigJSON)
+	for _, l := range next.LeagueConfig {
+		nextLeagues[leagueConfigKey(l)] = l
+		p, ok := prevLeagues[leagueConfigKey(l)]
+		if !ok {
+			changes = append(changes, fmt.Sprintf("added %s league %s (%s)", l.LeagueType, l.ID, l.Name))
+		} else if !reflect.DeepEqual(p, l) {
+			changes = append(changes, fmt.Sprintf("updated %s league %s (%s)", l.LeagueType, l.ID, l.Name))
+		}
+	}
+	for k, l := range prevLeagues {
+		if _, ok := nextLeagues[k]; !ok {
+			changes = append(changes, fmt.Sprintf("removed %s league %s (%s)", l.LeagueType, l.ID, l.Name))
+		}
+	}
+
+	if len(changes) == 0 {
+		changes = append(changes, "no changes")
+	}
+	return changes
+}
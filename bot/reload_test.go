@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/craigatron/football-gobot/config"
+)
+
+const testConfig = `{
+	"appId": "app",
+	"token": "token",
+	"command_guild_ids": ["guild"],
+	"reacc_config": {"reaccs": [{"pattern": "eagles", "reacc": "🦅"}]},
+	"espn_config": {"year": 2022, "swid": "swid", "s2": "s2"},
+	"sleeper_config": {"token": "sleeper"},
+	"leagues": [
+		{"type": "espn", "id": "1", "name": "Work League"},
+		{"type": "sleeper", "id": "2", "name": "Family League"}
+	],
+	"storage": {"type": "sqlite", "path": "gobot.db"},
+	"jobs": {"update_scores": "*/10 * * * *"},
+	"output": {"type": "local", "path": "site"}
+}`
+
+// parseTestConfig loads testConfig with the JSON in change merged over its
+// top level keys.
+func parseTestConfig(t *testing.T, change string) *config.JSON {
+	t.Helper()
+	c := &config.JSON{}
+	if err := json.Unmarshal([]byte(testConfig), c); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(change), c); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestConfigChanges(t *testing.T) {
+	tests := []struct {
+		name   string
+		change string
+		want   []string
+	}{
+		{
+			name:   "nothing",
+			change: `{}`,
+			want:   []string{"no changes"},
+		},
+		{
+			name:   "token",
+			change: `{"token": "new token"}`,
+			want:   []string{"Discord app ID or token changed, restart required to take effect"},
+		},
+		{
+			name:   "command guilds",
+			change: `{"command_guild_ids": ["guild", "other guild"]}`,
+			want:   []string{"command guilds changed, restart required to take effect"},
+		},
+		{
+			name:   "reaccs",
+			change: `{"reacc_config": {"reaccs": [{"pattern": "eagles", "reacc": "🦅"}], "ignore_reaccs": [{"user_id": "1", "ignore_reacc": "🦅"}]}}`,
+			want:   []string{"reaccs: 1 -> 1, ignored reaccs: 0 -> 1"},
+		},
+		{
+			name:   "storage, jobs and output",
+			change: `{"storage": {"type": "firestore", "project": "p"}, "jobs": {"update_scores": "@hourly"}, "output": {"type": "gcs", "bucket": "b"}}`,
+			want: []string{
+				"storage config changed, restart required to take effect",
+				"job schedules changed, restart required to take effect",
+				"output config changed, restart required for update-scores to write to it",
+			},
+		},
+		{
+			name:   "provider credentials",
+			change: `{"espn_config": {"year": 2023, "swid": "swid", "s2": "s2"}, "sleeper_config": {"token": "new"}}`,
+			want:   []string{"ESPN config changed", "Sleeper config changed"},
+		},
+		{
+			name: "leagues",
+			change: `{"leagues": [
+				{"type": "sleeper", "id": "2", "name": "Family League", "bot_update_channels": ["c"]},
+				{"type": "sleeper", "id": "3", "name": "Dynasty League"}
+			]}`,
+			want: []string{
+				"updated sleeper league 2 (Family League)",
+				"added sleeper league 3 (Dynasty League)",
+				"removed espn league 1 (Work League)",
+			},
+		},
+		{
+			name:   "leagues reordered",
+			change: `{"leagues": [{"type": "sleeper", "id": "2", "name": "Family League"}, {"type": "espn", "id": "1", "name": "Work League"}]}`,
+			want:   []string{"no changes"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := configChanges(parseTestConfig(t, `{}`), parseTestConfig(t, tt.change))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("configChanges() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// deferAfter is how long a command can run before it's sent a deferred
+// response, leaving a margin under Discord's three second deadline.
+const deferAfter = 2 * time.Second
+
+// pendingResponse tracks whether a command has been answered yet, so a slow
+// one can be deferred and its deferred response edited later.
+type pendingResponse struct {
+	mu        sync.Mutex
+	responded bool
+	// deferring is closed once a deferred response has been sent, or is nil
+	// if there hasn't been one.  deferred is whether it was sent
+	// successfully.
+	deferring chan struct{}
+	deferred  bool
+}
+
+// waitDeferred waits for a deferred response that's being sent and returns
+// whether the command was deferred.
+func (p *pendingResponse) waitDeferred() bool {
+	p.mu.Lock()
+	deferring := p.deferring
+	p.mu.Unlock()
+	if deferring == nil {
+		return false
+	}
+	<-deferring
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.deferred
+}
+
+// pendingResponses maps the IDs of commands being handled to their
+// *pendingResponse.
+var pendingResponses sync.Map
+
+// runCommand looks up the command's channel and league and calls its handler,
+// sending a deferred response if it hasn't responded within deferAfter of
+// starting, lookups included.  A deferred command that returns without
+// responding is told something went wrong, rather than being left thinking.
+func runCommand(c command, s *discordgo.Session, i *discordgo.InteractionCreate) {
+	p := &pendingResponse{}
+	pendingResponses.Store(i.ID, p)
+	defer pendingResponses.Delete(i.ID)
+
+	timer := time.AfterFunc(deferAfter, func() {
+		p.mu.Lock()
+		if p.responded {
+			p.mu.Unlock()
+			return
+		}
+		deferring := make(chan struct{})
+		p.deferring = deferring
+		p.mu.Unlock()
+
+		err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		})
+		if err != nil {
+			log.Printf("error deferring /%s: %s", c.name, err)
+		}
+		p.mu.Lock()
+		p.deferred = err == nil
+		p.mu.Unlock()
+		close(deferring)
+	})
+	if league, channel, problem := commandContext(c, s, i); problem != "" {
+		respondPrivately(s, i, problem)
+	} else {
+		c.handler(s, i, league, channel)
+	}
+	timer.Stop()
+
+	p.mu.Lock()
+	responded := p.responded
+	p.mu.Unlock()
+	if !responded && p.waitDeferred() {
+		respond(s, i, &discordgo.InteractionResponseData{Content: "something went wrong"})
+	}
+}
+
+// respond answers a command, editing its deferred response if it's been
+// deferred.  Deferred responses can't be made ephemeral, so ephemeral ones
+// replace the deferred response with an ephemeral follow-up instead.
+func respond(s *discordgo.Session, i *discordgo.InteractionCreate, data *discordgo.InteractionResponseData) {
+	p := &pendingResponse{}
+	if v, ok := pendingResponses.Load(i.ID); ok {
+		p = v.(*pendingResponse)
+	}
+	p.mu.Lock()
+	p.responded = true
+	p.mu.Unlock()
+	// a deferred response being sent has to arrive before it can be edited
+	deferred := p.waitDeferred()
+
+	var err error
+	switch {
+	case !deferred:
+		err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: data,
+		})
+	case data.Flags&discordgo.MessageFlagsEphemeral != 0:
+		if err := s.InteractionResponseDelete(i.Interaction); err != nil {
+			log.Printf("error deleting deferred response: %s", err)
+		}
+		_, err = s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+			Content:         data.Content,
+			Embeds:          data.Embeds,
+			Files:           data.Files,
+			AllowedMentions: data.AllowedMentions,
+			Flags:           discordgo.MessageFlagsEphemeral,
+		})
+	default:
+		edit := &discordgo.WebhookEdit{
+			Content:         &data.Content,
+			Files:           data.Files,
+			AllowedMentions: data.AllowedMentions,
+		}
+		if len(data.Embeds) > 0 {
+			edit.Embeds = &data.Embeds
+		}
+		if len(data.Components) > 0 {
+			edit.Components = &data.Components
+		}
+		_, err = s.InteractionResponseEdit(i.Interaction, edit)
+	}
+	if err != nil {
+		log.Printf("error responding to %s: %s", interactionName(i), err)
+	}
+}
+
+// interactionName describes an interaction for logs, e.g. "/standings".  Only
+// commands have a name, so other interactions are described by type and ID.
+func interactionName(i *discordgo.InteractionCreate) string {
+	if i.Type == discordgo.InteractionApplicationCommand || i.Type == discordgo.InteractionApplicationCommandAutocomplete {
+		return "/" + i.ApplicationCommandData().Name
+	}
+	return fmt.Sprintf("%s interaction %s", i.Type, i.ID)
+}
+
+// respondContent answers a command with just a message.
+func respondContent(s *discordgo.Session, i *discordgo.InteractionCreate, content string) {
+	respond(s, i, &discordgo.InteractionResponseData{Content: content})
+}
+
+// respondPrivately answers a command with a message only the user can see.
+func respondPrivately(s *discordgo.Session, i *discordgo.InteractionCreate, content string) {
+	respond(s, i, &discordgo.InteractionResponseData{
+		Content: content,
+		Flags:   discordgo.MessageFlagsEphemeral,
+	})
+}
+
+// respondError logs why a command failed and tells the user what couldn't be
+// done.
+func respondError(s *discordgo.Session, i *discordgo.InteractionCreate, content string, err error) {
+	log.Printf("error handling %s: %s: %s", interactionName(i), content, err)
+	respondContent(s, i, content)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestInteractionName(t *testing.T) {
+	tests := []struct {
+		name string
+		i    *discordgo.Interaction
+		want string
+	}{
+		{
+			name: "command",
+			i:    &discordgo.Interaction{ID: "1", Type: discordgo.InteractionApplicationCommand, Data: discordgo.ApplicationCommandInteractionData{Name: "standings"}},
+			want: "/standings",
+		},
+		{
+			name: "autocomplete",
+			i:    &discordgo.Interaction{ID: "2", Type: discordgo.InteractionApplicationCommandAutocomplete, Data: discordgo.ApplicationCommandInteractionData{Name: "player"}},
+			want: "/player",
+		},
+		{
+			name: "button",
+			i:    &discordgo.Interaction{ID: "3", Type: discordgo.InteractionMessageComponent, Data: discordgo.MessageComponentInteractionData{CustomID: "activity|x"}},
+			want: "MessageComponent interaction 3",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := interactionName(&discordgo.InteractionCreate{Interaction: tt.i}); got != tt.want {
+				t.Errorf("interactionName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPendingResponseWaitDeferred(t *testing.T) {
+	if (&pendingResponse{}).waitDeferred() {
+		t.Error("waitDeferred() without a deferral = true, want false")
+	}
+
+	for _, sent := range []bool{true, false} {
+		p := &pendingResponse{deferring: make(chan struct{})}
+		go func(sent bool) {
+			time.Sleep(10 * time.Millisecond)
+			p.mu.Lock()
+			p.deferred = sent
+			p.mu.Unlock()
+			close(p.deferring)
+		}(sent)
+		if got := p.waitDeferred(); got != sent {
+			t.Errorf("waitDeferred() while deferring = %t, want %t", got, sent)
+		}
+	}
+}
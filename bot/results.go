@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/craigatron/football-gobot/config"
+	"github.com/craigatron/football-gobot/store"
+)
+
+const resultsNotifyInterval = 5 * time.Minute
+
+// resultsEmbed formats a closed week's final scores for posting to a league's
+// update channels.
+func resultsEmbed(league config.League, teams map[int64]config.Team, week int, results []store.Result) *discordgo.MessageEmbed {
+	fields := make([]*discordgo.MessageEmbedField, 0, len(results))
+	for _, r := range results {
+		home := fmt.Sprintf("%.2f", r.HomeScore)
+		away := fmt.Sprintf("%.2f", r.AwayScore)
+		outcome := "tie"
+		switch r.WinnerID {
+		case r.HomeTeamID:
+			home = "**" + home + "**"
+			outcome = teams[r.HomeTeamID].Name + " wins"
+		case r.AwayTeamID:
+			away = "**" + away + "**"
+			outcome = teams[r.AwayTeamID].Name + " wins"
+		}
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:  fmt.Sprintf("%s vs %s", teams[r.HomeTeamID].Name, teams[r.AwayTeamID].Name),
+			Value: fmt.Sprintf("%s - %s, %s", home, away, outcome),
+		})
+	}
+	return &discordgo.MessageEmbed{
+		Title:  fmt.Sprintf("%s week %d final results", league.Config().Name, week),
+		URL:    chartURL(league, week, "index.html"),
+		Fields: fields,
+	}
+}
+
+// notifyResults periodically posts the final results of newly closed weeks
+// to each league's bot_update_channels.  The last posted week is recorded in
+// the store so nothing is posted twice across restarts.
+func notifyResults(s *discordgo.Session) {
+	for range time.Tick(resultsNotifyInterval) {
+		for _, league := range currentState().leagues() {
+			if len(league.Config().BotUpdateChannels) == 0 {
+				continue
+			}
+			if err := notifyLeagueResults(s, league); err != nil {
+				log.Printf("error notifying results for %s league %s: %s", league.Type(), league.ID(), err)
+			}
+		}
+	}
+}
+
+func notifyLeagueResults(s *discordgo.Session, league config.League) error {
+	ctx := context.Background()
+	leagueYear, err := db.LeagueYear(ctx, league)
+	if err != nil {
+		return err
+	}
+
+	// when several weeks were closed at once, e.g. the first time a season
+	// is closed out, only post the latest
+	week := leagueYear.ResultsNotified + 1
+	if week < leagueYear.ClosedWeek {
+		week = leagueYear.ClosedWeek
+	}
+	if week > leagueYear.ClosedWeek {
+		return nil
+	}
+
+	results, err := db.Results(ctx, league, week)
+	if err != nil {
+		return err
+	}
+	embed := resultsEmbed(league, league.Teams(), week, results)
+	for _, c := range league.Config().BotUpdateChannels {
+		if _, err := s.ChannelMessageSendEmbed(c, embed); err != nil {
+			log.Printf("error posting week %d results to channel %s: %s", week, c, err)
+		}
+	}
+	return db.UpdateLeagueYear(ctx, league, store.LeagueYear{ResultsNotified: week})
+}
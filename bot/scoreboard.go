@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/craigatron/football-gobot/config"
+)
+
+func handleScoreboardCommand(s *discordgo.Session, i *discordgo.InteractionCreate, league config.League, channel *discordgo.Channel) {
+	currentWeek, err := league.CurrentWeek()
+	if err != nil {
+		respondError(s, i, fmt.Sprintf("could not get %s league status", league.Type()), err)
+		return
+	}
+	week := currentWeek
+	for _, o := range i.ApplicationCommandData().Options {
+		if o.Name == "week" {
+			week = int(o.IntValue())
+		}
+	}
+
+	matchups, err := league.Matchups(week)
+	if err != nil {
+		respondError(s, i, fmt.Sprintf("could not get week %d matchups", week), err)
+		return
+	}
+
+	// projections are only available for the current week
+	projections := make(map[int64]float64)
+	if week == currentWeek {
+		p, err := league.Projections()
+		if err != nil {
+			log.Printf("error getting projections: %s\n", err)
+		}
+		for _, projection := range p {
+			projections[projection.TeamID] = projection.Projection
+		}
+	}
+
+	teams := league.Teams()
+	fields := make([]*discordgo.MessageEmbedField, 0, len(matchups))
+	for _, m := range matchups {
+		value := fmt.Sprintf("%.2f - %.2f", m.HomeScore, m.AwayScore)
+		homeProjection, hasHome := projections[m.HomeTeamID]
+		awayProjection, hasAway := projections[m.AwayTeamID]
+		if hasHome && hasAway {
+			value += fmt.Sprintf("\nprojected %.2f - %.2f", homeProjection, awayProjection)
+		}
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:  fmt.Sprintf("%s vs %s", teams[m.HomeTeamID].Name, teams[m.AwayTeamID].Name),
+			Value: value,
+		})
+	}
+
+	respond(s, i, &discordgo.InteractionResponseData{
+		Embeds: []*discordgo.MessageEmbed{
+			{
+				Title:  fmt.Sprintf("Week %d scoreboard", week),
+				Fields: fields,
+			},
+		},
+	})
+}
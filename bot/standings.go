@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/craigatron/football-gobot/config"
+)
+
+func handleStandingsCommand(s *discordgo.Session, i *discordgo.InteractionCreate, league config.League, channel *discordgo.Channel) {
+	standings, err := league.Standings()
+	if err != nil {
+		respondError(s, i, "could not get standings for league", err)
+		return
+	}
+
+	teams := league.Teams()
+	var sb strings.Builder
+	sb.WriteString("```\n")
+	fmt.Fprintf(&sb, "%-2s %-20s %-8s %7s %7s %-4s %s\n", "#", "Team", "W-L-T", "PF", "PA", "Strk", "Div")
+	for rank, st := range standings {
+		div := "-"
+		if st.Division != "" {
+			div = fmt.Sprintf("%d %s", st.DivisionRank, st.Division)
+		}
+		fmt.Fprintf(&sb, "%-2d %-20s %-8s %7.2f %7.2f %-4s %s\n",
+			rank+1,
+			truncate(teams[st.TeamID].Name, 20),
+			fmt.Sprintf("%d-%d-%d", st.Wins, st.Losses, st.Ties),
+			st.PointsFor,
+			st.PointsAgainst,
+			st.Streak,
+			div)
+	}
+	sb.WriteString("```")
+
+	respond(s, i, &discordgo.InteractionResponseData{
+		Embeds: []*discordgo.MessageEmbed{
+			{
+				Title:       fmt.Sprintf("%s %s standings", league.Config().Name, league.Season()),
+				Description: sb.String(),
+			},
+		},
+	})
+}
+
+// truncate shortens s to at most n runes so it fits in a table column.
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
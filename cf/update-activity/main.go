@@ -4,15 +4,13 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"os"
 	"time"
 
 	// Need this for cloud functions
 	_ "github.com/GoogleCloudPlatform/functions-framework-go/funcframework"
 
-	"cloud.google.com/go/firestore"
-	"github.com/craigatron/espn-fantasy-go"
 	"github.com/craigatron/football-gobot/config"
+	"github.com/craigatron/football-gobot/store"
 )
 
 // PubsubMessage is the type of the message triggering this function.
@@ -35,79 +33,77 @@ func UpdateActivity(ctx context.Context, m PubsubMessage) error {
 		return err
 	}
 
-	fsClient, err := firestore.NewClient(ctx, os.Getenv("PROJECT"))
+	db, err := store.New(ctx, conf.StorageConfig)
 	if err != nil {
-		log.Printf("error creating firestore client: %s", err)
+		log.Printf("error opening store: %s", err)
 		return err
 	}
+	defer db.Close()
 
+	leagueList := make([]config.League, 0, len(leagues))
 	for _, league := range leagues {
-		if league.LeagueType == config.LeagueTypeESPN {
-			err = processESPNLeague(ctx, fsClient, league)
-			if err != nil {
-				log.Printf("error processing ESPN league %s: %s", league.ESPNLeague.ID, err)
-			}
-		} else {
-			log.Printf("skipping sleeper league %s", league.SleeperLeague.ID)
-		}
+		leagueList = append(leagueList, league)
+	}
+	if err := Run(ctx, db, leagueList); err != nil {
+		log.Print(err)
 	}
 
 	return nil
 }
 
-func processESPNLeague(ctx context.Context, fsClient *firestore.Client, league *config.LeagueClient) error {
-	leagueYearKey := fmt.Sprintf("leagues/espn-%s/years/%d", league.ESPNLeague.ID, league.ESPNLeague.Year)
-	log.Printf("processing key %s", leagueYearKey)
-	leagueYear := fsClient.Doc(leagueYearKey)
+// Run saves new activity for each league.  Leagues that fail are logged and
+// skipped, and counted in the returned error.
+func Run(ctx context.Context, db store.Store, leagues []config.League) error {
+	failed := 0
+	for _, league := range leagues {
+		if err := processLeague(ctx, db, league); err != nil {
+			log.Printf("error processing %s league %s: %s", league.Type(), league.ID(), err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d leagues failed", failed, len(leagues))
+	}
+	return nil
+}
+
+func processLeague(ctx context.Context, db store.Store, league config.League) error {
+	log.Printf("processing key %s", config.LeagueYearKey(league))
 
-	doc, err := leagueYear.Get(ctx)
+	leagueYear, err := db.LeagueYear(ctx, league)
 	if err != nil {
 		return err
 	}
-	var updateTimestamp time.Time
-	leagueData := doc.Data()
-	if u, ok := leagueData["activity_updated"]; ok {
-		updateTimestamp = u.(time.Time)
-	} else {
+	updateTimestamp := leagueYear.ActivityUpdated
+	if updateTimestamp.IsZero() {
 		updateTimestamp = time.UnixMilli(0)
 	}
 
-	batch := fsClient.Batch()
-	if _, ok := leagueData["config"]; !ok {
-
-		members := make([]espn.LeagueMemberJSON, 0)
-		for _, m := range league.ESPNLeague.Members {
-			members = append(members, *m)
+	if leagueYear.Teams == nil {
+		members := make([]config.Member, 0)
+		for _, m := range league.Members() {
+			members = append(members, m)
 		}
-		teams := make([]map[string]interface{}, 0)
-		for _, t := range league.ESPNLeague.Teams {
-			teams = append(teams, map[string]interface{}{
-				"id":           t.ID,
-				"abbreviation": t.Abbreviation,
-				"name":         t.Name,
-				"owners":       t.Owners,
-			})
+		teams := make([]config.Team, 0)
+		for _, t := range league.Teams() {
+			teams = append(teams, t)
 		}
 		log.Printf("adding config: %v", teams)
-		batch.Set(leagueYear, map[string]interface{}{
-			"config": map[string]interface{}{
-				"members": members,
-				"teams":   teams,
-			},
-		}, firestore.MergeAll)
+		if err := db.UpdateLeagueYear(ctx, league, store.LeagueYear{Members: members, Teams: teams}); err != nil {
+			return err
+		}
 	} else {
 		log.Print("not adding config")
 	}
 
-	activityCollection := fsClient.Collection(fmt.Sprintf("%s/activity", leagueYearKey))
-
 	newUpdateTime := time.Now()
 	offset := 0
+	newActivity := make([]config.Activity, 0)
 
 Loop:
 	for {
 		log.Printf("processing offset %d", offset)
-		ra, err := league.ESPNLeague.RecentActivity(25, offset)
+		ra, err := league.RecentActivity(25, offset)
 		offset += 25
 		if err != nil {
 			return err
@@ -122,18 +118,9 @@ Loop:
 				log.Printf("activity %v older than last update %v, stopping update", activity, updateTimestamp)
 				break Loop
 			}
-			fsActivity := activityCollection.Doc(activity.ESPNID)
-			batch.Set(fsActivity, map[string]interface{}{
-				"actions":   activity.Actions,
-				"timestamp": activity.Timestamp,
-			})
+			newActivity = append(newActivity, activity)
 		}
 	}
 
-	batch.Set(leagueYear, map[string]interface{}{"activity_updated": newUpdateTime}, firestore.MergeAll)
-	_, err = batch.Commit(ctx)
-	if err != nil {
-		return err
-	}
-	return nil
+	return db.AddActivity(ctx, league, newActivity, newUpdateTime)
 }
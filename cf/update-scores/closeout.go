@@ -0,0 +1,43 @@
+package updatescores
+
+import (
+	"context"
+	"log"
+
+	"github.com/craigatron/football-gobot/config"
+	"github.com/craigatron/football-gobot/store"
+)
+
+// closeWeeks saves the final results of every completed week that hasn't
+// been closed yet, oldest first.  A week is complete once the league has
+// moved on to the next one, or once all of its NFL games are over, since
+// the league doesn't move on after its final week.  It returns the last
+// closed week.
+func closeWeeks(ctx context.Context, db store.Store, league config.League, currentWeek int, currentFinal bool) (int, error) {
+	leagueYear, err := db.LeagueYear(ctx, league)
+	if err != nil {
+		return 0, err
+	}
+
+	lastComplete := currentWeek - 1
+	if currentFinal {
+		lastComplete = currentWeek
+	}
+	closed := leagueYear.ClosedWeek
+	for week := closed + 1; week <= lastComplete; week++ {
+		matchups, err := league.Matchups(week)
+		if err != nil {
+			return closed, err
+		}
+		results := make([]store.Result, 0, len(matchups))
+		for _, m := range matchups {
+			results = append(results, store.NewResult(m))
+		}
+		log.Printf("closing week %d with %d results", week, len(results))
+		if err := db.CloseWeek(ctx, league, week, results); err != nil {
+			return closed, err
+		}
+		closed = week
+	}
+	return closed, nil
+}
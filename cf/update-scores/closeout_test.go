@@ -0,0 +1,67 @@
+package updatescores
+
+import (
+	"context"
+	"testing"
+
+	"github.com/craigatron/football-gobot/config"
+	"github.com/craigatron/football-gobot/config/configtest"
+	"github.com/craigatron/football-gobot/store"
+)
+
+func TestCloseWeeks(t *testing.T) {
+	ctx := context.Background()
+	league := configtest.League{MatchupsByWeek: map[int][]config.Matchup{
+		1: {{ID: 1, Week: 1, HomeTeamID: 1, AwayTeamID: 2, HomeScore: 100, AwayScore: 90}},
+		2: {{ID: 2, Week: 2, HomeTeamID: 2, AwayTeamID: 1, HomeScore: 80, AwayScore: 95}},
+		3: {{ID: 3, Week: 3, HomeTeamID: 1, AwayTeamID: 2, HomeScore: 70, AwayScore: 75}},
+	}}
+	tests := []struct {
+		name          string
+		alreadyClosed int
+		currentWeek   int
+		currentFinal  bool
+		want          int
+	}{
+		{"first week in progress", 0, 1, false, 0},
+		{"first week over", 0, 1, true, 1},
+		{"weeks before the current one", 0, 3, false, 2},
+		{"final week over", 0, 3, true, 3},
+		{"already closed", 3, 3, true, 3},
+		{"only new weeks", 1, 3, false, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := store.NewMemory()
+			for week := 1; week <= tt.alreadyClosed; week++ {
+				if err := db.CloseWeek(ctx, league, week, nil); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := closeWeeks(ctx, db, league, tt.currentWeek, tt.currentFinal)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("closeWeeks(%d, %t) = %d, want %d", tt.currentWeek, tt.currentFinal, got, tt.want)
+			}
+			leagueYear, err := db.LeagueYear(ctx, league)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if leagueYear.ClosedWeek != tt.want {
+				t.Errorf("ClosedWeek = %d, want %d", leagueYear.ClosedWeek, tt.want)
+			}
+			for week := tt.alreadyClosed + 1; week <= tt.want; week++ {
+				results, err := db.Results(ctx, league, week)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(results) != 1 || results[0].MatchupID != int64(week) {
+					t.Errorf("week %d results = %+v, want matchup %d", week, results, week)
+				}
+			}
+		})
+	}
+}
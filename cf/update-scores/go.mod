@@ -3,23 +3,25 @@ module github.com/craigatron/football-gobot/cf/update-scores
 go 1.19
 
 require (
-	cloud.google.com/go/firestore v1.6.1
 	cloud.google.com/go/storage v1.23.0
 	github.com/GoogleCloudPlatform/functions-framework-go v1.5.3
-	github.com/craigatron/espn-fantasy-go v0.0.2-0.20220908003037-8c46118e0ace
 	github.com/craigatron/football-gobot/config v0.0.0
-	github.com/craigatron/sleeper-go v0.0.0-20220907013444-753ab69ad51f
-	google.golang.org/api v0.85.0
+	github.com/craigatron/football-gobot/store v0.0.0
 )
 
 replace github.com/craigatron/football-gobot/config => ../../config
 
+replace github.com/craigatron/football-gobot/store => ../../store
+
 require (
 	cloud.google.com/go v0.102.1 // indirect
 	cloud.google.com/go/compute v1.7.0 // indirect
+	cloud.google.com/go/firestore v1.6.1 // indirect
 	cloud.google.com/go/functions v1.0.0 // indirect
 	cloud.google.com/go/iam v0.3.0 // indirect
 	github.com/cloudevents/sdk-go/v2 v2.6.1 // indirect
+	github.com/craigatron/espn-fantasy-go v0.0.2-0.20220908003037-8c46118e0ace // indirect
+	github.com/craigatron/sleeper-go v0.0.0-20220907013444-753ab69ad51f // indirect
 	github.com/golang/groupcache v0.0.0-20200121045136-8c9f03a8e57e // indirect
 	github.com/golang/protobuf v1.5.2 // indirect
 	github.com/google/go-cmp v0.5.8 // indirect
@@ -28,19 +30,21 @@ require (
 	github.com/googleapis/gax-go/v2 v2.4.0 // indirect
 	github.com/googleapis/go-type-adapters v1.0.0 // indirect
 	github.com/json-iterator/go v1.1.10 // indirect
+	github.com/mattn/go-sqlite3 v1.14.15 // indirect
 	github.com/modern-go/concurrent v0.0.0-20180228061459-e0a39a4cb421 // indirect
 	github.com/modern-go/reflect2 v0.0.0-20180701023420-4b7aa43c6742 // indirect
 	go.opencensus.io v0.23.0 // indirect
 	go.uber.org/atomic v1.4.0 // indirect
 	go.uber.org/multierr v1.1.0 // indirect
 	go.uber.org/zap v1.10.0 // indirect
-	golang.org/x/net v0.0.0-20220617184016-355a448f1bc9 // indirect
-	golang.org/x/oauth2 v0.0.0-20220608161450-d0670ef3b1eb // indirect
-	golang.org/x/sys v0.0.0-20220615213510-4f61da869c0c // indirect
+	golang.org/x/net v0.0.0-20220909164309-bea034e7d591 // indirect
+	golang.org/x/oauth2 v0.0.0-20220822191816-0ebed06d0094 // indirect
+	golang.org/x/sys v0.0.0-20220728004956-3c1f35247d10 // indirect
 	golang.org/x/text v0.3.7 // indirect
 	golang.org/x/xerrors v0.0.0-20220609144429-65e65417b02f // indirect
+	google.golang.org/api v0.96.0 // indirect
 	google.golang.org/appengine v1.6.7 // indirect
-	google.golang.org/genproto v0.0.0-20220617124728-180714bec0ad // indirect
-	google.golang.org/grpc v1.47.0 // indirect
+	google.golang.org/genproto v0.0.0-20220624142145-8cd45d7dbd1f // indirect
+	google.golang.org/grpc v1.49.0 // indirect
 	google.golang.org/protobuf v1.28.0 // indirect
 )
@@ -1,26 +1,21 @@
 package updatescores
 
 import (
+	"bytes"
 	"context"
 	_ "embed"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"html/template"
-	"io/ioutil"
 	"log"
-	"os"
 	"time"
 
 	// Need this for cloud functions
 	_ "github.com/GoogleCloudPlatform/functions-framework-go/funcframework"
-	"google.golang.org/api/iterator"
 
-	"cloud.google.com/go/firestore"
-	"cloud.google.com/go/storage"
-	"github.com/craigatron/espn-fantasy-go"
 	"github.com/craigatron/football-gobot/config"
-	"github.com/craigatron/sleeper-go"
+	"github.com/craigatron/football-gobot/store"
 )
 
 // PubsubMessage is the type of the message triggering this function.
@@ -43,199 +38,126 @@ func UpdateScores(ctx context.Context, m PubsubMessage) error {
 		return err
 	}
 
-	fsClient, err := firestore.NewClient(ctx, os.Getenv("PROJECT"))
+	db, err := store.New(ctx, conf.StorageConfig)
 	if err != nil {
-		log.Printf("error creating firestore client: %s", err)
+		log.Printf("error opening store: %s", err)
 		return err
 	}
+	defer db.Close()
 
-	storageClient, err := storage.NewClient(ctx)
+	output, err := NewOutput(ctx, conf.OutputConfig)
 	if err != nil {
-		log.Printf("error creating storage client: %s", err)
+		log.Printf("error creating output: %s", err)
 		return err
 	}
 
-	projectionBucket := storageClient.Bucket(os.Getenv("PROJECTION_BUCKET"))
-
+	leagueList := make([]config.League, 0, len(leagues))
 	for _, league := range leagues {
-		if league.LeagueType == config.LeagueTypeESPN {
-			err = processESPNLeague(ctx, fsClient, league.ESPNLeague, projectionBucket)
-			if err != nil {
-				log.Printf("error processing ESPN league %s: %s", league.ESPNLeague.ID, err)
-			}
-		} else if league.LeagueType == config.LeagueTypeSleeper {
-			err = processSleeperLeague(ctx, fsClient, league.SleeperLeague, projectionBucket)
-			if err != nil {
-				log.Printf("error processing Sleeper league %s: %s", league.SleeperLeague.ID, err)
-			}
-		} else {
-			log.Printf("skipping unknown league type %s", league.LeagueType)
-		}
+		leagueList = append(leagueList, league)
+	}
+	if err := Run(ctx, db, leagueList, output); err != nil {
+		log.Print(err)
 	}
 
 	return nil
 }
 
-type Projection struct {
-	MatchupID  int64   `firestore:"matchup_id"`
-	Projection float64 `firestore:"projection"`
-	TeamID     int64   `firestore:"team_id"`
-	Timestamp  int64   `firestore:"timestamp"`
-}
-
-func processESPNLeague(ctx context.Context, fsClient *firestore.Client, league *espn.League, projectionBucket *storage.BucketHandle) error {
-	leagueYearKey := fmt.Sprintf("leagues/espn-%s/years/%d", league.ID, league.Year)
-	log.Printf("processing key %s", leagueYearKey)
-	leagueYear := fsClient.Doc(leagueYearKey)
-
-	batch := fsClient.Batch()
-
-	weeksCollection := fsClient.Collection(fmt.Sprintf("%s/weeks", leagueYearKey))
-	currentWeekDoc := weeksCollection.Doc(fmt.Sprintf("%d", league.CurrentWeek))
-	projectionsCollection := currentWeekDoc.Collection("projections")
-
-	allProjections := make([]Projection, 0)
-	allProjectionsIter := projectionsCollection.Documents(ctx)
-	for {
-		doc, err := allProjectionsIter.Next()
-		if err == iterator.Done {
-			break
-		}
-		if err != nil {
-			return err
-		}
-		var projectionData Projection
-		if err := doc.DataTo(&projectionData); err != nil {
-			return err
+// Run closes out each league's finished weeks, then saves the current week's
+// projections and writes its charts to output, if set, unless the current
+// week was closed too.  Leagues that fail are logged and skipped, and counted
+// in the returned error.
+func Run(ctx context.Context, db store.Store, leagues []config.League, output Output) error {
+	failed := 0
+	for _, league := range leagues {
+		if err := processLeague(ctx, db, league, output); err != nil {
+			log.Printf("error processing %s league %s: %s", league.Type(), league.ID(), err)
+			failed++
 		}
-		allProjections = append(allProjections, projectionData)
 	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d leagues failed", failed, len(leagues))
+	}
+	return nil
+}
 
-	newUpdateTime := time.Now()
-	// UnixMilli doesn't exist in Go 1.16, which is the latest version cloud functions has :(
-	newUpdateMillis := newUpdateTime.UnixNano() / (int64(time.Millisecond) / int64(time.Nanosecond))
-
-	matchups, err := league.Scoreboard()
+func processLeague(ctx context.Context, db store.Store, league config.League, output Output) error {
+	week, err := league.CurrentWeek()
 	if err != nil {
 		return err
 	}
+	log.Printf("processing key %s", config.LeagueYearKey(league))
 
-	for _, matchup := range matchups {
-		homeDoc := projectionsCollection.Doc(fmt.Sprintf("%d-%d", newUpdateMillis, matchup.HomeTeam.ID))
-		homeData := Projection{
-			MatchupID:  matchup.ID,
-			Projection: matchup.HomeScore,
-			TeamID:     matchup.HomeTeam.ID,
-			Timestamp:  newUpdateMillis,
-		}
-		batch.Set(homeDoc, homeData)
-		allProjections = append(allProjections, homeData)
-
-		awayDoc := projectionsCollection.Doc(fmt.Sprintf("%d-%d", newUpdateMillis, matchup.AwayTeam.ID))
-		awayData := Projection{
-			MatchupID:  matchup.ID,
-			Projection: matchup.AwayScore,
-			TeamID:     matchup.AwayTeam.ID,
-			Timestamp:  newUpdateMillis,
-		}
-		batch.Set(awayDoc, awayData)
-		allProjections = append(allProjections, awayData)
-
+	games, gamesErr := config.NFLGames(league.Season(), week)
+	if gamesErr != nil {
+		log.Printf("error getting NFL games, not closing week %d: %s", week, gamesErr)
 	}
-
-	batch.Set(leagueYear, map[string]interface{}{"scores_updated": newUpdateTime}, firestore.MergeAll)
-	_, err = batch.Commit(ctx)
+	closedWeek, err := closeWeeks(ctx, db, league, week, gamesErr == nil && games.Final())
 	if err != nil {
 		return err
 	}
-
-	teamIDToName := make(map[int64]string)
-	for _, team := range league.Teams {
-		teamIDToName[int64(team.ID)] = team.Name
+	if week <= closedWeek {
+		log.Printf("week %d is closed, not saving projections", week)
+		return nil
 	}
-	writeHTML(projectionBucket, config.LeagueTypeESPN, league.ID, fmt.Sprintf("%d", league.Year), league.CurrentWeek, teamIDToName, allProjections)
-	return nil
-}
 
-func processSleeperLeague(ctx context.Context, fsClient *firestore.Client, league *sleeper.League, projectionBucket *storage.BucketHandle) error {
-	status, err := league.Client.GetNflStatus()
+	allProjections, err := db.Projections(ctx, league, week)
 	if err != nil {
 		return err
 	}
-	leagueYearKey := fmt.Sprintf("leagues/sleeper-%s/years/%s", league.ID, status.Season)
-	log.Printf("processing key %s", leagueYearKey)
-	leagueYear := fsClient.Doc(leagueYearKey)
-
-	batch := fsClient.Batch()
-
-	weeksCollection := fsClient.Collection(fmt.Sprintf("%s/weeks", leagueYearKey))
-	currentWeekDoc := weeksCollection.Doc(fmt.Sprintf("%d", status.Week))
-	projectionsCollection := currentWeekDoc.Collection("projections")
-
-	allProjections := make([]Projection, 0)
-	allProjectionsIter := projectionsCollection.Documents(ctx)
-	for {
-		doc, err := allProjectionsIter.Next()
-		if err == iterator.Done {
-			break
-		}
-		if err != nil {
-			return err
-		}
-		var projectionData Projection
-		if err := doc.DataTo(&projectionData); err != nil {
-			return err
-		}
-		allProjections = append(allProjections, projectionData)
-	}
 
 	newUpdateTime := time.Now()
-	// UnixMilli doesn't exist in Go 1.16, which is the latest version cloud functions has :(
-	newUpdateMillis := newUpdateTime.UnixNano() / (int64(time.Millisecond) / int64(time.Nanosecond))
+	newUpdateMillis := newUpdateTime.UnixMilli()
 
-	projections, err := league.GetProjections()
+	projections, err := league.Projections()
 	if err != nil {
 		return err
 	}
 
-	for _, projection := range projections {
-		doc := projectionsCollection.Doc(fmt.Sprintf("%d-%d", newUpdateMillis, projection.Matchup.RosterID))
-		data := Projection{
-			MatchupID:  int64(projection.Matchup.MatchupID),
-			Projection: projection.Projection,
-			TeamID:     int64(projection.Matchup.RosterID),
-			Timestamp:  newUpdateMillis,
-		}
-		batch.Set(doc, data)
-		allProjections = append(allProjections, data)
+	// without lineups and game statuses there's no way to tell how settled a
+	// matchup is, so save the projections without win probabilities rather
+	// than not at all
+	winProbs := make(map[int64]float64)
+	if lineups, err := league.Lineups(week); err != nil {
+		log.Printf("error getting lineups, not computing win probabilities: %s", err)
+	} else if gamesErr != nil {
+		log.Printf("error getting NFL games, not computing win probabilities: %s", gamesErr)
+	} else {
+		winProbs = winProbabilities(projections, lineups, gameRemaining(league, games))
 	}
 
-	batch.Set(leagueYear, map[string]interface{}{"scores_updated": newUpdateTime}, firestore.MergeAll)
-	_, err = batch.Commit(ctx)
-	if err != nil {
+	newProjections := make([]store.Projection, 0, len(projections))
+	for _, projection := range projections {
+		newProjections = append(newProjections, store.Projection{
+			MatchupID:      projection.MatchupID,
+			Projection:     projection.Projection,
+			TeamID:         projection.TeamID,
+			Timestamp:      newUpdateMillis,
+			WinProbability: winProbs[projection.TeamID],
+		})
+	}
+	if err := db.AddProjections(ctx, league, week, newProjections, newUpdateTime); err != nil {
 		return err
 	}
+	allProjections = append(allProjections, newProjections...)
 
-	teamIDToName := make(map[int64]string)
-	for _, team := range league.Rosters {
-		owner := league.Users[team.OwnerID]
-		var teamName string
-		if owner.Metadata.TeamName != "" {
-			teamName = owner.Metadata.TeamName
-		} else {
-			teamName = owner.DisplayName
-		}
-		teamIDToName[int64(team.RosterID)] = teamName
+	if output == nil {
+		log.Printf("no output configured, not writing HTML")
+		return nil
 	}
 
-	writeHTML(projectionBucket, config.LeagueTypeSleeper, league.ID, league.Season, status.Week, teamIDToName, allProjections)
+	teamIDToName := make(map[int64]string)
+	for id, team := range league.Teams() {
+		teamIDToName[id] = team.Name
+	}
 
-	return nil
+	return writeHTML(ctx, output, league, week, teamIDToName, allProjections)
 }
 
 type ChartProjection struct {
 	Timestamp  string  `json:"timestamp"`
 	Projection float64 `json:"projection"`
+	// WinProbability is null for projections saved before it was tracked.
+	WinProbability *float64 `json:"win_probability"`
 }
 
 type TemplateData struct {
@@ -262,14 +184,20 @@ var matchupTemplate string
 //go:embed week_index.html
 var indexTemplate string
 
-func writeHTML(projectionBucket *storage.BucketHandle, leagueType config.LeagueType, leagueID string, season string, week int, teamIDToName map[int64]string, projections []Projection) error {
-	log.Printf("generating HTML for %s league %s", leagueType, leagueID)
+func writeHTML(ctx context.Context, output Output, league config.League, week int, teamIDToName map[int64]string, projections []store.Projection) error {
+	log.Printf("generating HTML for %s league %s", league.Type(), league.ID())
 
-	dir, err := ioutil.TempDir("", fmt.Sprintf("%s-%s", leagueType, leagueID))
-	if err != nil {
-		return err
+	// projections saved before win probabilities were tracked have zero for
+	// both teams in the matchup
+	hasWinProb := make(map[int64]map[int64]bool)
+	for _, p := range projections {
+		if p.WinProbability > 0 {
+			if _, ok := hasWinProb[p.MatchupID]; !ok {
+				hasWinProb[p.MatchupID] = make(map[int64]bool)
+			}
+			hasWinProb[p.MatchupID][p.Timestamp] = true
+		}
 	}
-	defer os.RemoveAll(dir)
 
 	matchupProjections := make(map[int64]map[int64][]ChartProjection)
 	for _, p := range projections {
@@ -280,9 +208,15 @@ func writeHTML(projectionBucket *storage.BucketHandle, leagueType config.LeagueT
 			matchupProjections[p.MatchupID][p.TeamID] = make([]ChartProjection, 0)
 		}
 		tp := matchupProjections[p.MatchupID][p.TeamID]
+		var winProb *float64
+		if hasWinProb[p.MatchupID][p.Timestamp] {
+			wp := p.WinProbability
+			winProb = &wp
+		}
 		matchupProjections[p.MatchupID][p.TeamID] = append(tp, ChartProjection{
-			Timestamp:  time.Unix(0, p.Timestamp*int64(time.Millisecond)).Format(time.RFC3339),
-			Projection: p.Projection,
+			Timestamp:      time.Unix(0, p.Timestamp*int64(time.Millisecond)).Format(time.RFC3339),
+			Projection:     p.Projection,
+			WinProbability: winProb,
 		})
 	}
 
@@ -321,25 +255,24 @@ func writeHTML(projectionBucket *storage.BucketHandle, leagueType config.LeagueT
 			Team2Data: string(team2Data),
 		}
 
-		ctx := context.Background()
-		objectName := fmt.Sprintf("%s/%s/%d/%d.html", leagueID, season, week, matchupID)
-		obj := projectionBucket.Object(objectName)
-		w := obj.NewWriter(ctx)
-		w.ContentType = "text/html"
-
-		err = matchupTmpl.Execute(w, templateData)
-		if err != nil {
+		buf := &bytes.Buffer{}
+		if err := matchupTmpl.Execute(buf, templateData); err != nil {
 			return err
 		}
-
-		if err := w.Close(); err != nil {
+		name := config.ChartPath(league, week, fmt.Sprintf("%d.html", matchupID))
+		if err := output.Write(ctx, name, "text/html", buf.Bytes()); err != nil {
 			return err
 		}
 
+		// link relative to the index if the output isn't served anywhere
+		url := output.URL(name)
+		if url == "" {
+			url = fmt.Sprintf("%d.html", matchupID)
+		}
 		indexData.Matchups = append(indexData.Matchups, IndexMatchupData{
 			Team1Name: teamIDToName[teams[0]],
 			Team2Name: teamIDToName[teams[1]],
-			URL:       fmt.Sprintf("https://storage.googleapis.com/%s/%s/%s/%d/%d.html", os.Getenv("PROJECTION_BUCKET"), leagueID, season, week, matchupID),
+			URL:       url,
 		})
 	}
 
@@ -349,18 +282,9 @@ func writeHTML(projectionBucket *storage.BucketHandle, leagueType config.LeagueT
 		return err
 	}
 
-	ctx := context.Background()
-	objectName := fmt.Sprintf("%s/%s/%d/index.html", leagueID, season, week)
-	obj := projectionBucket.Object(objectName)
-	w := obj.NewWriter(ctx)
-	w.ContentType = "text/html"
-
-	err = tmpl.Execute(w, indexData)
-	if err != nil {
+	buf := &bytes.Buffer{}
+	if err := tmpl.Execute(buf, indexData); err != nil {
 		return err
 	}
-
-	w.Close()
-
-	return nil
+	return output.Write(ctx, config.ChartPath(league, week, "index.html"), "text/html", buf.Bytes())
 }
@@ -0,0 +1,236 @@
+package updatescores
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/craigatron/football-gobot/config"
+)
+
+// Output is somewhere the generated pages are written and served from.
+type Output interface {
+	// Write saves data as the file name, a slash-separated path relative to
+	// the output root, replacing anything already there.
+	Write(ctx context.Context, name string, contentType string, data []byte) error
+	// URL is the public URL name is served at, or "" if it isn't served.
+	URL(name string) string
+}
+
+// NewOutput creates the output selected by the config, or returns nil if
+// none is configured.
+func NewOutput(ctx context.Context, c config.OutputConfigJSON) (Output, error) {
+	switch c.Type {
+	case "":
+		return nil, nil
+	case "gcs":
+		client, err := storage.NewClient(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return &gcsOutput{config: c, bucket: client.Bucket(c.Bucket)}, nil
+	case "local":
+		return &localOutput{config: c}, nil
+	case "s3":
+		return &s3Output{config: c, client: &http.Client{Timeout: time.Minute}}, nil
+	}
+	return nil, fmt.Errorf("unknown output type %s", c.Type)
+}
+
+// gcsOutput writes to a Cloud Storage bucket.
+type gcsOutput struct {
+	config config.OutputConfigJSON
+	bucket *storage.BucketHandle
+}
+
+func (o *gcsOutput) Write(ctx context.Context, name string, contentType string, data []byte) error {
+	w := o.bucket.Object(name).NewWriter(ctx)
+	w.ContentType = contentType
+	if _, err := w.Write(data); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+func (o *gcsOutput) URL(name string) string {
+	return o.config.PublicURL(name)
+}
+
+// localOutput writes to a local directory, e.g. one served by a static file
+// server in development.
+type localOutput struct {
+	config config.OutputConfigJSON
+}
+
+func (o *localOutput) Write(ctx context.Context, name string, contentType string, data []byte) error {
+	path := filepath.Join(o.config.Path, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+func (o *localOutput) URL(name string) string {
+	return o.config.PublicURL(name)
+}
+
+// s3Output writes to a bucket on an S3-compatible endpoint, signing requests
+// with AWS signature version 4.
+type s3Output struct {
+	config config.OutputConfigJSON
+	client *http.Client
+}
+
+func (o *s3Output) Write(ctx context.Context, name string, contentType string, data []byte) error {
+	u := fmt.Sprintf("%s/%s/%s", strings.TrimSuffix(o.config.Endpoint, "/"), o.config.Bucket, name)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	o.sign(req, data, time.Now().UTC())
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("writing %s to s3 bucket %s: %s: %s", name, o.config.Bucket, resp.Status, body)
+	}
+	return nil
+}
+
+func (o *s3Output) URL(name string) string {
+	return o.config.PublicURL(name)
+}
+
+// sign adds a signature version 4 Authorization header to req, signing its
+// host and every header already set on it.
+func (o *s3Output) sign(req *http.Request, body []byte, now time.Time) {
+	region := o.config.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		trimmed := make([]string, 0, len(values))
+		for _, v := range values {
+			trimmed = append(trimmed, strings.Join(strings.Fields(v), " "))
+		}
+		headers[strings.ToLower(name)] = strings.Join(trimmed, ",")
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	canonicalHeaders := &strings.Builder{}
+	for _, name := range names {
+		fmt.Fprintf(canonicalHeaders, "%s:%s\n", name, headers[name])
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		canonicalURI(req.URL.Path),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := fmt.Sprintf("%s/%s/s3/aws4_request", day, region)
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, sha256Hex([]byte(canonicalRequest))}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+o.config.SecretAccessKey), day)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		o.config.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// canonicalURI encodes each segment of an S3 object path.  S3 paths are
+// only encoded once, unlike other services'.
+func canonicalURI(path string) string {
+	if path == "" {
+		return "/"
+	}
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = uriEncode(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// canonicalQuery encodes query parameters sorted by name, then value.
+func canonicalQuery(query url.Values) string {
+	names := make([]string, 0, len(query))
+	encoded := make(map[string][]string)
+	for name, values := range query {
+		n := uriEncode(name)
+		names = append(names, n)
+		for _, v := range values {
+			encoded[n] = append(encoded[n], uriEncode(v))
+		}
+		sort.Strings(encoded[n])
+	}
+	sort.Strings(names)
+	params := make([]string, 0, len(names))
+	for _, n := range names {
+		for _, v := range encoded[n] {
+			params = append(params, n+"="+v)
+		}
+	}
+	return strings.Join(params, "&")
+}
+
+// uriEncode percent-encodes everything but the characters signature version
+// 4 leaves unreserved, which is stricter than url.PathEscape.
+func uriEncode(s string) string {
+	b := &strings.Builder{}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
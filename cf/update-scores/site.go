@@ -0,0 +1,512 @@
+package updatescores
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/craigatron/football-gobot/config"
+	"github.com/craigatron/football-gobot/store"
+)
+
+// activityPageSize is how many transactions are on each activity log page.
+const activityPageSize = 50
+
+//go:embed season_index.html
+var seasonIndexTemplate string
+
+//go:embed team.html
+var teamTemplate string
+
+//go:embed activity.html
+var activityTemplate string
+
+// BuildSite is the entry point for the cloud function that regenerates each
+// league's season site.
+func BuildSite(ctx context.Context, m PubsubMessage) error {
+	log.Printf("Starting build site run with data %s", m)
+	conf, err := config.LoadConfig()
+	if err != nil {
+		log.Printf("error loading config: %s", err)
+		return err
+	}
+
+	leagues, err := config.CreateLeagueClients(conf)
+	if err != nil {
+		log.Printf("error creating leagues: %s", err)
+		return err
+	}
+
+	db, err := store.New(ctx, conf.StorageConfig)
+	if err != nil {
+		log.Printf("error opening store: %s", err)
+		return err
+	}
+	defer db.Close()
+
+	output, err := NewOutput(ctx, conf.OutputConfig)
+	if err != nil {
+		log.Printf("error creating output: %s", err)
+		return err
+	}
+
+	leagueList := make([]config.League, 0, len(leagues))
+	for _, league := range leagues {
+		leagueList = append(leagueList, league)
+	}
+	if err := RunSite(ctx, db, leagueList, output); err != nil {
+		log.Print(err)
+	}
+
+	return nil
+}
+
+// RunSite writes a static site for each league's season to output: a season
+// index with a standings progression chart, a page per team and the activity
+// log.  Links between pages are relative so the site works wherever the
+// output is served.  Leagues that fail are logged and skipped, and counted in
+// the returned error.
+func RunSite(ctx context.Context, db store.Store, leagues []config.League, output Output) error {
+	if output == nil {
+		return errors.New("no output configured, not building site")
+	}
+	failed := 0
+	for _, league := range leagues {
+		if err := writeSite(ctx, db, league, output); err != nil {
+			log.Printf("error building site for %s league %s: %s", league.Type(), league.ID(), err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d leagues failed", failed, len(leagues))
+	}
+	return nil
+}
+
+// teamWeek is a team's result for one week.
+type teamWeek struct {
+	Week          int
+	MatchupID     int64
+	TeamID        int64
+	OpponentID    int64
+	Score         float64
+	OpponentScore float64
+	// Final is false for the current week, whose scores can still change.
+	Final bool
+}
+
+// Result is "W", "L" or "T", or "" if the week isn't final.
+func (w teamWeek) Result() string {
+	switch {
+	case !w.Final:
+		return ""
+	case w.Score > w.OpponentScore:
+		return "W"
+	case w.Score < w.OpponentScore:
+		return "L"
+	}
+	return "T"
+}
+
+// seasonResults returns each team's weekly results through currentWeek,
+// keyed by team ID and ordered by week.  Closed weeks use their final
+// results, and other weeks the last saved projections.  Weeks with neither
+// are left out.
+func seasonResults(ctx context.Context, db store.Store, league config.League, currentWeek int) (map[int64][]teamWeek, error) {
+	results := make(map[int64][]teamWeek)
+	for week := 1; week <= currentWeek; week++ {
+		final, err := db.Results(ctx, league, week)
+		if err != nil {
+			return nil, err
+		}
+		if len(final) > 0 {
+			for _, r := range final {
+				results[r.HomeTeamID] = append(results[r.HomeTeamID], teamWeek{
+					Week:          week,
+					MatchupID:     r.MatchupID,
+					TeamID:        r.HomeTeamID,
+					OpponentID:    r.AwayTeamID,
+					Score:         r.HomeScore,
+					OpponentScore: r.AwayScore,
+					Final:         true,
+				})
+				results[r.AwayTeamID] = append(results[r.AwayTeamID], teamWeek{
+					Week:          week,
+					MatchupID:     r.MatchupID,
+					TeamID:        r.AwayTeamID,
+					OpponentID:    r.HomeTeamID,
+					Score:         r.AwayScore,
+					OpponentScore: r.HomeScore,
+					Final:         true,
+				})
+			}
+			continue
+		}
+
+		projections, err := db.Projections(ctx, league, week)
+		if err != nil {
+			return nil, err
+		}
+
+		// projections are oldest first, so the last one for each team wins
+		latest := make(map[int64]store.Projection)
+		for _, p := range projections {
+			latest[p.TeamID] = p
+		}
+		matchupTeams := make(map[int64][]store.Projection)
+		for _, p := range latest {
+			matchupTeams[p.MatchupID] = append(matchupTeams[p.MatchupID], p)
+		}
+
+		for matchupID, teams := range matchupTeams {
+			if len(teams) != 2 {
+				log.Printf("skipping week %d matchup %d with %d teams", week, matchupID, len(teams))
+				continue
+			}
+			for i, t := range teams {
+				opp := teams[1-i]
+				results[t.TeamID] = append(results[t.TeamID], teamWeek{
+					Week:          week,
+					MatchupID:     matchupID,
+					TeamID:        t.TeamID,
+					OpponentID:    opp.TeamID,
+					Score:         t.Projection,
+					OpponentScore: opp.Projection,
+					Final:         week < currentWeek,
+				})
+			}
+		}
+	}
+	return results, nil
+}
+
+// chartedMatchups returns the IDs of the matchups with projections saved in
+// each week, which are the ones the scores job wrote a chart page for.
+func chartedMatchups(ctx context.Context, db store.Store, league config.League, weeks []int) (map[int]map[int64]bool, error) {
+	charted := make(map[int]map[int64]bool)
+	for _, week := range weeks {
+		projections, err := db.Projections(ctx, league, week)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range projections {
+			if charted[week] == nil {
+				charted[week] = make(map[int64]bool)
+			}
+			charted[week][p.MatchupID] = true
+		}
+	}
+	return charted, nil
+}
+
+// standingsThrough totals each team's final results up to and including week.
+func standingsThrough(results map[int64][]teamWeek, week int) []config.Standing {
+	standings := make([]config.Standing, 0, len(results))
+	for teamID, weeks := range results {
+		s := config.Standing{TeamID: teamID}
+		for _, w := range weeks {
+			if w.Week > week || !w.Final {
+				continue
+			}
+			switch w.Result() {
+			case "W":
+				s.Wins++
+			case "L":
+				s.Losses++
+			case "T":
+				s.Ties++
+			}
+			s.PointsFor += w.Score
+			s.PointsAgainst += w.OpponentScore
+		}
+		standings = append(standings, s)
+	}
+	sort.Slice(standings, func(i, j int) bool {
+		return standings[i].TeamID < standings[j].TeamID
+	})
+	config.SortStandings(standings)
+	return standings
+}
+
+func formatRecord(s config.Standing) string {
+	if s.Ties > 0 {
+		return fmt.Sprintf("%d-%d-%d", s.Wins, s.Losses, s.Ties)
+	}
+	return fmt.Sprintf("%d-%d", s.Wins, s.Losses)
+}
+
+type standingsChartTeam struct {
+	Name  string `json:"name"`
+	Ranks []int  `json:"ranks"`
+}
+
+type standingsChartData struct {
+	Weeks []int                `json:"weeks"`
+	Teams []standingsChartTeam `json:"teams"`
+}
+
+type SeasonTeamData struct {
+	Name   string
+	Record string
+	URL    string
+}
+
+type SeasonWeekData struct {
+	Week int
+	// URL is empty for weeks without a chart page.
+	URL string
+}
+
+type SeasonIndexData struct {
+	LeagueName    string
+	Season        string
+	Teams         []SeasonTeamData
+	Weeks         []SeasonWeekData
+	ActivityURL   string
+	StandingsData string
+}
+
+type TeamWeekData struct {
+	Week          int
+	Opponent      string
+	Score         float64
+	OpponentScore float64
+	Result        string
+	// MatchupURL is empty for matchups without a chart page.
+	MatchupURL  string
+	OpponentURL string
+}
+
+type TeamPageData struct {
+	LeagueName string
+	Season     string
+	SeasonURL  string
+	Name       string
+	Record     string
+	Weeks      []TeamWeekData
+}
+
+type ActivityEntryData struct {
+	Time    string
+	Actions []string
+}
+
+type ActivityPageData struct {
+	LeagueName string
+	Season     string
+	SeasonURL  string
+	Activity   []ActivityEntryData
+	NewerURL   string
+	OlderURL   string
+}
+
+func writeSite(ctx context.Context, db store.Store, league config.League, output Output) error {
+	log.Printf("building site for %s league %s", league.Type(), league.ID())
+
+	currentWeek, err := league.CurrentWeek()
+	if err != nil {
+		return err
+	}
+	results, err := seasonResults(ctx, db, league, currentWeek)
+	if err != nil {
+		return err
+	}
+	leagueYear, err := db.LeagueYear(ctx, league)
+	if err != nil {
+		return err
+	}
+
+	// prefer the teams saved at the start of the season, so old activity
+	// reads as it did at the time
+	teams := make(map[int64]config.Team)
+	for id, t := range league.Teams() {
+		teams[id] = t
+	}
+	for _, t := range leagueYear.Teams {
+		teams[t.ID] = t
+	}
+
+	templates := make(map[string]*template.Template)
+	for name, text := range map[string]string{
+		"season":   seasonIndexTemplate,
+		"team":     teamTemplate,
+		"activity": activityTemplate,
+	} {
+		tmpl, err := template.New(name).Parse(text)
+		if err != nil {
+			return err
+		}
+		templates[name] = tmpl
+	}
+	write := func(page string, tmpl string, data interface{}) error {
+		buf := &bytes.Buffer{}
+		if err := templates[tmpl].Execute(buf, data); err != nil {
+			return err
+		}
+		return output.Write(ctx, config.SitePath(league, page), "text/html", buf.Bytes())
+	}
+
+	// weeks map to whether they're final
+	weekSet := make(map[int]bool)
+	for _, weeks := range results {
+		for _, w := range weeks {
+			weekSet[w.Week] = w.Final
+		}
+	}
+	weeks := make([]int, 0, len(weekSet))
+	for w := range weekSet {
+		weeks = append(weeks, w)
+	}
+	sort.Ints(weeks)
+	charted, err := chartedMatchups(ctx, db, league, weeks)
+	if err != nil {
+		return err
+	}
+
+	// standings progression over the final weeks
+	chart := standingsChartData{Weeks: make([]int, 0), Teams: make([]standingsChartTeam, 0)}
+	chartTeams := make(map[int64]*standingsChartTeam)
+	for _, w := range weeks {
+		if !weekSet[w] {
+			break
+		}
+		chart.Weeks = append(chart.Weeks, w)
+		for rank, s := range standingsThrough(results, w) {
+			ct, ok := chartTeams[s.TeamID]
+			if !ok {
+				ct = &standingsChartTeam{Name: teams[s.TeamID].Name}
+				chartTeams[s.TeamID] = ct
+			}
+			ct.Ranks = append(ct.Ranks, rank+1)
+		}
+	}
+	for _, ct := range chartTeams {
+		chart.Teams = append(chart.Teams, *ct)
+	}
+	sort.Slice(chart.Teams, func(i, j int) bool {
+		return chart.Teams[i].Name < chart.Teams[j].Name
+	})
+	standingsData, err := json.Marshal(chart)
+	if err != nil {
+		return err
+	}
+
+	indexData := SeasonIndexData{
+		LeagueName:    league.Config().Name,
+		Season:        league.Season(),
+		Teams:         make([]SeasonTeamData, 0),
+		Weeks:         make([]SeasonWeekData, 0, len(weeks)),
+		ActivityURL:   "activity/1.html",
+		StandingsData: string(standingsData),
+	}
+	for _, w := range weeks {
+		weekData := SeasonWeekData{Week: w}
+		if len(charted[w]) > 0 {
+			weekData.URL = fmt.Sprintf("%d/index.html", w)
+		}
+		indexData.Weeks = append(indexData.Weeks, weekData)
+	}
+
+	for _, s := range standingsThrough(results, currentWeek) {
+		team := teams[s.TeamID]
+		indexData.Teams = append(indexData.Teams, SeasonTeamData{
+			Name:   team.Name,
+			Record: formatRecord(s),
+			URL:    fmt.Sprintf("teams/%d.html", team.ID),
+		})
+
+		teamData := TeamPageData{
+			LeagueName: league.Config().Name,
+			Season:     league.Season(),
+			SeasonURL:  "../index.html",
+			Name:       team.Name,
+			Record:     formatRecord(s),
+			Weeks:      make([]TeamWeekData, 0, len(results[team.ID])),
+		}
+		for _, w := range results[team.ID] {
+			weekData := TeamWeekData{
+				Week:          w.Week,
+				Opponent:      teams[w.OpponentID].Name,
+				Score:         w.Score,
+				OpponentScore: w.OpponentScore,
+				Result:        w.Result(),
+				OpponentURL:   fmt.Sprintf("%d.html", w.OpponentID),
+			}
+			if charted[w.Week][w.MatchupID] {
+				weekData.MatchupURL = fmt.Sprintf("../%d/%d.html", w.Week, w.MatchupID)
+			}
+			teamData.Weeks = append(teamData.Weeks, weekData)
+		}
+		if err := write(fmt.Sprintf("teams/%d.html", team.ID), "team", teamData); err != nil {
+			return err
+		}
+	}
+
+	if err := writeActivityPages(ctx, db, league, teams, write); err != nil {
+		return err
+	}
+
+	return write("index.html", "season", indexData)
+}
+
+// writeActivityPages writes the season's activity newest first, timed in the
+// league's timezone and split into pages of activityPageSize.  There's always
+// at least one page.
+func writeActivityPages(ctx context.Context, db store.Store, league config.League, teams map[int64]config.Team, write func(page string, tmpl string, data interface{}) error) error {
+	loc, err := league.Config().Location()
+	if err != nil {
+		return err
+	}
+	activity, err := db.ActivitySince(ctx, league, 0)
+	if err != nil {
+		return err
+	}
+
+	entries := make([]ActivityEntryData, 0, len(activity))
+	for i := len(activity) - 1; i >= 0; i-- {
+		a := activity[i]
+		actions := make([]string, 0, len(a.Actions))
+		for _, action := range a.Actions {
+			actions = append(actions, config.FormatAction(league, teams, action))
+		}
+		entries = append(entries, ActivityEntryData{
+			Time:    time.UnixMilli(a.Timestamp).In(loc).Format("Mon Jan 2 3:04PM"),
+			Actions: actions,
+		})
+	}
+
+	pages := (len(entries) + activityPageSize - 1) / activityPageSize
+	if pages == 0 {
+		pages = 1
+	}
+	for page := 1; page <= pages; page++ {
+		start := (page - 1) * activityPageSize
+		end := start + activityPageSize
+		if end > len(entries) {
+			end = len(entries)
+		}
+		data := ActivityPageData{
+			LeagueName: league.Config().Name,
+			Season:     league.Season(),
+			SeasonURL:  "../index.html",
+			Activity:   entries[start:end],
+		}
+		if page > 1 {
+			data.NewerURL = fmt.Sprintf("%d.html", page-1)
+		}
+		if page < pages {
+			data.OlderURL = fmt.Sprintf("%d.html", page+1)
+		}
+		if err := write(fmt.Sprintf("activity/%d.html", page), "activity", data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package updatescores
+
+import (
+	"math"
+
+	"github.com/craigatron/football-gobot/config"
+)
+
+// playerSDRatio is the standard deviation of a player's remaining points as a
+// fraction of their remaining projection.
+const playerSDRatio = 0.5
+
+// remainingVariance is the variance of a team's remaining points, treating
+// each starter's remaining points as independent.  A starter's remaining
+// projection is their projection scaled by the fraction of their game left,
+// so players whose games are over add nothing however they scored.
+func remainingVariance(lineup config.Lineup, gameRemaining func(playerID string) float64) float64 {
+	variance := 0.0
+	for _, p := range lineup.Players {
+		if !p.Starter {
+			continue
+		}
+		remaining := math.Max(p.Projected, 0) * gameRemaining(p.PlayerID)
+		variance += math.Pow(playerSDRatio*remaining, 2)
+	}
+	return variance
+}
+
+// gameRemaining returns the fraction of each player's game left to play.
+// Players on bye have nothing left, and players the league can't look up are
+// assumed to have their whole game left.
+func gameRemaining(league config.League, games config.NFLWeek) func(playerID string) float64 {
+	return func(playerID string) float64 {
+		player, ok := league.Player(playerID)
+		if !ok {
+			return 1
+		}
+		g, ok := games.Game(player.NFLTeam)
+		if !ok {
+			return 0
+		}
+		return g.Remaining()
+	}
+}
+
+// winProbability is the chance a team projected for projection beats an
+// opponent projected for oppProjection, assuming the final margin is normally
+// distributed with the given variance.
+func winProbability(projection float64, oppProjection float64, variance float64) float64 {
+	margin := projection - oppProjection
+	if variance <= 0 {
+		switch {
+		case margin > 0:
+			return 1
+		case margin < 0:
+			return 0
+		}
+		return 0.5
+	}
+	return 0.5 * math.Erfc(-margin/math.Sqrt(2*variance))
+}
+
+// winProbabilities returns each team's chance of winning its matchup, keyed
+// by team ID.  Teams in matchups that don't have exactly two teams are left
+// out.
+func winProbabilities(projections []config.Projection, lineups map[int64]config.Lineup, gameRemaining func(playerID string) float64) map[int64]float64 {
+	matchups := make(map[int64][]config.Projection)
+	for _, p := range projections {
+		matchups[p.MatchupID] = append(matchups[p.MatchupID], p)
+	}
+
+	probabilities := make(map[int64]float64)
+	for _, teams := range matchups {
+		if len(teams) != 2 {
+			continue
+		}
+		variance := remainingVariance(lineups[teams[0].TeamID], gameRemaining) + remainingVariance(lineups[teams[1].TeamID], gameRemaining)
+		p := winProbability(teams[0].Projection, teams[1].Projection, variance)
+		probabilities[teams[0].TeamID] = p
+		probabilities[teams[1].TeamID] = 1 - p
+	}
+	return probabilities
+}
@@ -0,0 +1,153 @@
+package updatescores
+
+import (
+	"math"
+	"testing"
+
+	"github.com/craigatron/football-gobot/config"
+	"github.com/craigatron/football-gobot/config/configtest"
+)
+
+func approxEqual(a float64, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestWinProbability(t *testing.T) {
+	tests := []struct {
+		name          string
+		projection    float64
+		oppProjection float64
+		variance      float64
+		want          float64
+	}{
+		{"even", 100, 100, 25, 0.5},
+		{"one standard deviation ahead", 105, 100, 25, 0.8413447460685429},
+		{"one standard deviation behind", 95, 100, 25, 0.15865525393145707},
+		{"two standard deviations ahead", 110, 100, 25, 0.9772498680518208},
+		{"settled win", 101, 100, 0, 1},
+		{"settled loss", 99, 100, 0, 0},
+		{"settled tie", 100, 100, 0, 0.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := winProbability(tt.projection, tt.oppProjection, tt.variance); !approxEqual(got, tt.want) {
+				t.Errorf("winProbability(%g, %g, %g) = %g, want %g", tt.projection, tt.oppProjection, tt.variance, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRemainingVariance(t *testing.T) {
+	league := configtest.League{PlayersByID: map[string]config.Player{
+		"final":     {ID: "final", NFLTeam: "BUF"},
+		"halftime":  {ID: "halftime", NFLTeam: "WAS"},
+		"upcoming":  {ID: "upcoming", NFLTeam: "KC"},
+		"bye":       {ID: "bye", NFLTeam: "DAL"},
+		"freeAgent": {ID: "freeAgent"},
+	}}
+	games := config.NFLWeek{
+		"BUF": {Started: true, Final: true, Period: 4},
+		"WSH": {Started: true, Period: 2},
+		"KC":  {},
+	}
+	remaining := gameRemaining(league, games)
+	tests := []struct {
+		name    string
+		players []config.LineupPlayer
+		want    float64
+	}{
+		{
+			name:    "finished player under their projection",
+			players: []config.LineupPlayer{{PlayerID: "final", Starter: true, Points: 4, Projected: 12}},
+			want:    0,
+		},
+		{
+			name:    "finished player over their projection",
+			players: []config.LineupPlayer{{PlayerID: "final", Starter: true, Points: 30, Projected: 12}},
+			want:    0,
+		},
+		{
+			name:    "player who passed their projection at halftime",
+			players: []config.LineupPlayer{{PlayerID: "halftime", Starter: true, Points: 20, Projected: 16}},
+			// half of 16 left, with a standard deviation of half that
+			want: 16,
+		},
+		{
+			name:    "player who hasn't played",
+			players: []config.LineupPlayer{{PlayerID: "upcoming", Starter: true, Projected: 10}},
+			want:    25,
+		},
+		{
+			name:    "unknown player",
+			players: []config.LineupPlayer{{PlayerID: "unknown", Starter: true, Projected: 10}},
+			want:    25,
+		},
+		{
+			name: "players without games",
+			players: []config.LineupPlayer{
+				{PlayerID: "bye", Starter: true, Projected: 10},
+				{PlayerID: "freeAgent", Starter: true, Projected: 10},
+			},
+			want: 0,
+		},
+		{
+			name: "bench players",
+			players: []config.LineupPlayer{
+				{PlayerID: "upcoming", Starter: true, Projected: 10},
+				{PlayerID: "halftime", Projected: 16},
+			},
+			want: 25,
+		},
+		{
+			name: "independent starters",
+			players: []config.LineupPlayer{
+				{PlayerID: "upcoming", Starter: true, Projected: 10},
+				{PlayerID: "halftime", Starter: true, Projected: 16},
+				{PlayerID: "final", Starter: true, Points: 8, Projected: 12},
+			},
+			want: 41,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := remainingVariance(config.Lineup{Players: tt.players}, remaining)
+			if !approxEqual(got, tt.want) {
+				t.Errorf("remainingVariance(%+v) = %g, want %g", tt.players, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWinProbabilities(t *testing.T) {
+	projections := []config.Projection{
+		{MatchupID: 1, TeamID: 1, Projection: 105},
+		{MatchupID: 1, TeamID: 2, Projection: 100},
+		{MatchupID: 2, TeamID: 3, Projection: 90},
+		{MatchupID: 2, TeamID: 4, Projection: 80},
+		// a bye week
+		{MatchupID: 3, TeamID: 5, Projection: 70},
+	}
+	lineups := map[int64]config.Lineup{
+		// 3^2 + 4^2 = 25
+		1: {TeamID: 1, Players: []config.LineupPlayer{{PlayerID: "a", Starter: true, Projected: 6}}},
+		2: {TeamID: 2, Players: []config.LineupPlayer{{PlayerID: "b", Starter: true, Projected: 8}}},
+	}
+	allLeft := func(playerID string) float64 { return 1 }
+
+	got := winProbabilities(projections, lineups, allLeft)
+	want := map[int64]float64{
+		1: 0.8413447460685429,
+		2: 0.15865525393145707,
+		// no lineups, so the matchup is settled
+		3: 1,
+		4: 0,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("winProbabilities() = %v, want %v", got, want)
+	}
+	for teamID, w := range want {
+		if !approxEqual(got[teamID], w) {
+			t.Errorf("winProbabilities()[%d] = %g, want %g", teamID, got[teamID], w)
+		}
+	}
+}
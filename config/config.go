@@ -3,16 +3,16 @@ package config
 import (
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
-	"io/ioutil"
 	"os"
-
-	"cloud.google.com/go/storage"
-	"github.com/craigatron/espn-fantasy-go"
-	"github.com/craigatron/sleeper-go"
+	"reflect"
+	"sort"
+	"time"
 )
 
+// defaultTimezone is the timezone used when the config doesn't set one.
+const defaultTimezone = "America/New_York"
+
 // LeagueConfigJSON is the JSON config for an individual league.
 type LeagueConfigJSON struct {
 	LeagueType         string   `json:"type"`
@@ -20,6 +20,77 @@ type LeagueConfigJSON struct {
 	ID                 string   `json:"id"`
 	DiscordCategoryIDs []string `json:"discord_category_ids"`
 	BotUpdateChannels  []string `json:"bot_update_channels"`
+	// Links seeds Discord user IDs to the ESPN member or Sleeper user ID that
+	// owns their team.  Links made with /link take precedence.
+	Links map[string]string `json:"links"`
+	// PowerRankings weights the RankingFormulas used by /powerrankings, by
+	// name.  Leagues without any are ranked by all-play record.
+	PowerRankings map[string]float64 `json:"power_rankings"`
+	// Timezone is the IANA timezone the league's times are shown in,
+	// defaulting to America/New_York.
+	Timezone string `json:"timezone"`
+}
+
+// Location returns the league's timezone.
+func (l LeagueConfigJSON) Location() (*time.Location, error) {
+	if l.Timezone == "" {
+		return time.LoadLocation(defaultTimezone)
+	}
+	return time.LoadLocation(l.Timezone)
+}
+
+// RankingWeights returns the league's power ranking weights, or
+// DefaultRankingWeights if it doesn't set any.
+func (l LeagueConfigJSON) RankingWeights() map[string]float64 {
+	if len(l.PowerRankings) == 0 {
+		return DefaultRankingWeights
+	}
+	return l.PowerRankings
+}
+
+// StorageConfigJSON selects where league data is stored.
+type StorageConfigJSON struct {
+	// Type is "firestore" (the default), "sqlite" or "memory".
+	Type string `json:"type"`
+	// Project is the GCP project for Firestore, defaulting to $PROJECT.
+	Project string `json:"project"`
+	// Path is the SQLite database file.
+	Path string `json:"path"`
+}
+
+// JobsConfigJSON schedules the update jobs to run inside the bot, for
+// deployments without Cloud Functions.  Each is a schedule accepted by
+// ParseSchedule; jobs left empty aren't run by the bot.  UpdateScores can
+// also be GameTimeSchedule to poll on GameTimeConfig.
+type JobsConfigJSON struct {
+	// Timezone is the IANA timezone cron schedules are evaluated in,
+	// defaulting to America/New_York.
+	Timezone       string             `json:"timezone"`
+	UpdateActivity string             `json:"update_activity"`
+	UpdateScores   string             `json:"update_scores"`
+	GameTimeConfig GameTimeConfigJSON `json:"game_time"`
+	// BuildSite regenerates each league's season site in the output.
+	BuildSite string `json:"build_site"`
+	// Recap posts the latest closed week's recap to each league's update
+	// channels, if it hasn't been posted yet.
+	Recap string `json:"recap"`
+}
+
+// Location returns the timezone cron schedules are evaluated in.
+func (c JobsConfigJSON) Location() (*time.Location, error) {
+	if c.Timezone == "" {
+		return time.LoadLocation(defaultTimezone)
+	}
+	return time.LoadLocation(c.Timezone)
+}
+
+// Schedule parses one of the jobs' schedules in the jobs' timezone.
+func (c JobsConfigJSON) Schedule(s string) (Schedule, error) {
+	loc, err := c.Location()
+	if err != nil {
+		return nil, err
+	}
+	return ParseSchedule(s, loc)
 }
 
 // JSON is the JSON config for various football-gobot mods.
@@ -27,6 +98,14 @@ type JSON struct {
 	AppID string `json:"appId"`
 	Token string `json:"token"`
 
+	// AdminChannel is an optional Discord channel for bot status notices.
+	AdminChannel string `json:"admin_channel"`
+
+	// CommandGuildIDs registers the bot's slash commands in just these
+	// Discord servers, where changes show up immediately, instead of
+	// globally.
+	CommandGuildIDs []string `json:"command_guild_ids"`
+
 	ReaccConfig struct {
 		Reaccs []struct {
 			Pattern string `json:"pattern"`
@@ -50,37 +129,63 @@ type JSON struct {
 	} `json:"sleeper_config"`
 
 	LeagueConfig []LeagueConfigJSON `json:"leagues"`
+
+	StorageConfig StorageConfigJSON `json:"storage"`
+
+	JobsConfig JobsConfigJSON `json:"jobs"`
+
+	OutputConfig OutputConfigJSON `json:"output"`
+
+	// unknownKeys are keys in the source JSON that aren't fields above.
+	unknownKeys []string
 }
 
-// LoadConfig fetches the config from GCS.
+// LoadConfig loads the config from the source selected by the environment.
+// See DefaultSource.
 func LoadConfig() (JSON, error) {
-	configBucket := os.Getenv("CONFIG_BUCKET")
-	configObject := os.Getenv("CONFIG_OBJECT")
-
-	c := JSON{}
-	if configBucket == "" || configObject == "" {
-		return c, errors.New("no CONFIG_BUCKET and/or CONFIG_OBJECT provided")
+	src, err := DefaultSource("")
+	if err != nil {
+		return JSON{}, err
 	}
+	return LoadConfigFrom(context.Background(), src)
+}
 
-	ctx := context.Background()
-	client, err := storage.NewClient(ctx)
+// LoadConfigFrom loads the config from the given source, applies any
+// overrides from the environment and validates the result.
+func LoadConfigFrom(ctx context.Context, src Source) (JSON, error) {
+	c, err := readConfig(ctx, src)
 	if err != nil {
 		return c, err
 	}
+	return c, c.Validate()
+}
 
-	r, err := client.Bucket(configBucket).Object(configObject).NewReader(ctx)
+// LoadBotConfigFrom is LoadConfigFrom, but validates the config for the bot
+// with ValidateBot.
+func LoadBotConfigFrom(ctx context.Context, src Source) (JSON, error) {
+	c, err := readConfig(ctx, src)
 	if err != nil {
 		return c, err
 	}
+	return c, c.ValidateBot()
+}
 
-	f, err := ioutil.ReadAll(r)
-	r.Close()
+// readConfig loads the config from the given source and applies any
+// overrides from the environment.
+func readConfig(ctx context.Context, src Source) (JSON, error) {
+	c := JSON{}
+	f, err := src.Read(ctx)
 	if err != nil {
-		return c, err
+		return c, fmt.Errorf("reading config from %s: %w", src, err)
 	}
 
-	err = json.Unmarshal(f, &c)
-	return c, err
+	if err := json.Unmarshal(f, &c); err != nil {
+		return c, fmt.Errorf("parsing config from %s: %w", src, err)
+	}
+	c.unknownKeys = findUnknownKeys(f, reflect.TypeOf(c), "")
+	sort.Strings(c.unknownKeys)
+	ApplyEnvOverrides(&c, os.LookupEnv)
+	return c, nil
 }
 
 // LeagueClientsKey is a key in the map returned by CreateLeagueClients.
@@ -89,50 +194,34 @@ type LeagueClientsKey struct {
 	LeagueID   string
 }
 
-// LeagueClient is an ESPN or Sleeper league client.
-type LeagueClient struct {
-	LeagueType    LeagueType
-	ESPNLeague    *espn.League
-	SleeperLeague *sleeper.League
-	LeagueConfig  LeagueConfigJSON
+// NewLeague creates an ESPN/Sleeper client for a single league in the given config.
+func NewLeague(c JSON, l LeagueConfigJSON) (League, error) {
+	if l.LeagueType == "sleeper" {
+		league, err := NewSleeperLeague(l, c.SleeperConfig.Token)
+		if err != nil {
+			return nil, err
+		}
+		return league, nil
+	} else if l.LeagueType == "espn" {
+		league, err := NewESPNLeague(l, c.ESPNConfig.Year, c.ESPNConfig.ESPNS2, c.ESPNConfig.SWID)
+		if err != nil {
+			return nil, err
+		}
+		return league, nil
+	}
+	return nil, fmt.Errorf("unknown league type %s", l.LeagueType)
 }
 
 // CreateLeagueClients creates ESPN/Sleeper clients based on the given config.
-func CreateLeagueClients(c JSON) (map[LeagueClientsKey]*LeagueClient, error) {
-	clients := make(map[LeagueClientsKey]*LeagueClient)
+func CreateLeagueClients(c JSON) (map[LeagueClientsKey]League, error) {
+	clients := make(map[LeagueClientsKey]League)
 
 	for _, l := range c.LeagueConfig {
-		if l.LeagueType == "sleeper" {
-			league, err := sleeper.NewLeague(l.ID, c.SleeperConfig.Token)
-			if err != nil {
-				return clients, err
-			}
-			lc := &LeagueClient{
-				LeagueType:    LeagueTypeSleeper,
-				SleeperLeague: &league,
-				LeagueConfig:  l,
-			}
-			clients[LeagueClientsKey{LeagueType: LeagueTypeSleeper, LeagueID: l.ID}] = lc
-		} else if l.LeagueType == "espn" {
-			var league espn.League
-			var err error
-			if c.ESPNConfig.ESPNS2 == "" && c.ESPNConfig.SWID == "" {
-				league, err = espn.NewPublicLeague(espn.GameTypeNfl, l.ID, c.ESPNConfig.Year)
-			} else {
-				league, err = espn.NewPrivateLeague(espn.GameTypeNfl, l.ID, c.ESPNConfig.Year, c.ESPNConfig.ESPNS2, c.ESPNConfig.SWID)
-			}
-			if err != nil {
-				return clients, err
-			}
-			lc := &LeagueClient{
-				LeagueType:   LeagueTypeESPN,
-				ESPNLeague:   &league,
-				LeagueConfig: l,
-			}
-			clients[LeagueClientsKey{LeagueType: LeagueTypeESPN, LeagueID: l.ID}] = lc
-		} else {
-			return clients, fmt.Errorf("unknown league type %s", l.LeagueType)
+		league, err := NewLeague(c, l)
+		if err != nil {
+			return clients, err
 		}
+		clients[LeagueClientsKey{LeagueType: league.Type(), LeagueID: l.ID}] = league
 	}
 
 	return clients, nil
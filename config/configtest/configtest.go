@@ -0,0 +1,146 @@
+// Package configtest provides a fake config.League for tests.
+package configtest
+
+import (
+	"fmt"
+
+	"github.com/craigatron/football-gobot/config"
+)
+
+// League is a config.League serving fixed data.  Every method works on the
+// zero value: the league is Sleeper league "1" in the 2022 season unless
+// set otherwise, and anything else not set is empty.  The maps and slices
+// are returned as is, so callers mustn't modify them.
+type League struct {
+	LeagueID     string
+	LeagueType   config.LeagueType
+	LeagueSeason string
+	Week         int
+
+	TeamsByID      map[int64]config.Team
+	MembersByID    map[string]config.Member
+	MatchupsByWeek map[int][]config.Matchup
+	// WeekProjections are the current week's projections.
+	WeekProjections []config.Projection
+	StandingsList   []config.Standing
+	LineupsByWeek   map[int]map[int64]config.Lineup
+	RostersByTeam   map[int64]config.Roster
+	// Activity is the league's transactions, newest first.
+	Activity    []config.Activity
+	PlayersByID map[string]config.Player
+	// SeasonPointsByPlayer is each player's season points, keyed by player
+	// ID.
+	SeasonPointsByPlayer map[string]float64
+	Settings             config.LeagueConfigJSON
+}
+
+// ID is LeagueID, or "1".
+func (l League) ID() string {
+	if l.LeagueID == "" {
+		return "1"
+	}
+	return l.LeagueID
+}
+
+// Type is LeagueType, or Sleeper.
+func (l League) Type() config.LeagueType {
+	if l.LeagueType == config.LeagueTypeUnknown {
+		return config.LeagueTypeSleeper
+	}
+	return l.LeagueType
+}
+
+// Season is LeagueSeason, or "2022".
+func (l League) Season() string {
+	if l.LeagueSeason == "" {
+		return "2022"
+	}
+	return l.LeagueSeason
+}
+
+// CurrentWeek is Week.
+func (l League) CurrentWeek() (int, error) {
+	return l.Week, nil
+}
+
+// Teams is TeamsByID.
+func (l League) Teams() map[int64]config.Team {
+	return l.TeamsByID
+}
+
+// Members is MembersByID.
+func (l League) Members() map[string]config.Member {
+	return l.MembersByID
+}
+
+// Matchups is the week's MatchupsByWeek.
+func (l League) Matchups(week int) ([]config.Matchup, error) {
+	return l.MatchupsByWeek[week], nil
+}
+
+// Projections is WeekProjections.
+func (l League) Projections() ([]config.Projection, error) {
+	return l.WeekProjections, nil
+}
+
+// Standings is StandingsList.
+func (l League) Standings() ([]config.Standing, error) {
+	return l.StandingsList, nil
+}
+
+// Lineups is the week's LineupsByWeek.
+func (l League) Lineups(week int) (map[int64]config.Lineup, error) {
+	return l.LineupsByWeek[week], nil
+}
+
+// Rosters is RostersByTeam.
+func (l League) Rosters() (map[int64]config.Roster, error) {
+	return l.RostersByTeam, nil
+}
+
+// RecentActivity pages through Activity.
+func (l League) RecentActivity(limit int, offset int) ([]config.Activity, error) {
+	if offset < 0 || limit < 0 {
+		return nil, fmt.Errorf("bad limit %d or offset %d", limit, offset)
+	}
+	if offset > len(l.Activity) {
+		offset = len(l.Activity)
+	}
+	end := offset + limit
+	if end > len(l.Activity) {
+		end = len(l.Activity)
+	}
+	return append([]config.Activity{}, l.Activity[offset:end]...), nil
+}
+
+// Player looks up a player in PlayersByID.
+func (l League) Player(id string) (config.Player, bool) {
+	p, ok := l.PlayersByID[id]
+	return p, ok
+}
+
+// Players is every player in PlayersByID.
+func (l League) Players() []config.Player {
+	players := make([]config.Player, 0, len(l.PlayersByID))
+	for _, p := range l.PlayersByID {
+		players = append(players, p)
+	}
+	return players
+}
+
+// SeasonPoints is the player's SeasonPointsByPlayer.
+func (l League) SeasonPoints(playerID string) (float64, error) {
+	return l.SeasonPointsByPlayer[playerID], nil
+}
+
+// Refresh does nothing.
+func (l League) Refresh() error {
+	return nil
+}
+
+// Config is Settings.
+func (l League) Config() config.LeagueConfigJSON {
+	return l.Settings
+}
+
+var _ config.League = League{}
@@ -0,0 +1,537 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/craigatron/espn-fantasy-go"
+)
+
+const espnLeagueURL = "https://fantasy.espn.com/apis/v3/games/ffl/seasons/%d/segments/0/leagues/%s"
+
+// ESPN lineup slots that don't count towards a team's score.
+const (
+	espnSlotBench = 20
+	espnSlotIR    = 21
+)
+
+// ESPN stat sources.
+const (
+	espnStatSourceActual    = 0
+	espnStatSourceProjected = 1
+)
+
+// espnScoringPeriodSeason is the scoring period of a player's season totals.
+const espnScoringPeriodSeason = 0
+
+var espnSlotNames = map[int]string{
+	0:  "QB",
+	2:  "RB",
+	3:  "RB/WR",
+	4:  "WR",
+	5:  "WR/TE",
+	6:  "TE",
+	7:  "OP",
+	16: "D/ST",
+	17: "K",
+	20: "BN",
+	21: "IR",
+	23: "FLEX",
+}
+
+// ESPNLeague is a League backed by the ESPN fantasy API.
+type ESPNLeague struct {
+	// league holds a *espnState that's replaced whole on Refresh, so it's
+	// safe to read while refreshing.
+	league atomic.Value
+	config LeagueConfigJSON
+
+	espnS2 string
+	swid   string
+
+	httpClient *http.Client
+}
+
+// NewESPNLeague creates a League for the given ESPN league config.
+func NewESPNLeague(l LeagueConfigJSON, year int, espnS2 string, swid string) (*ESPNLeague, error) {
+	league, err := loadESPNLeague(l.ID, year, espnS2, swid)
+	if err != nil {
+		return nil, err
+	}
+	el := &ESPNLeague{
+		config:     l,
+		espnS2:     espnS2,
+		swid:       swid,
+		httpClient: &http.Client{Timeout: time.Minute},
+	}
+	el.league.Store(league)
+	return el, nil
+}
+
+// espnState is the league as loaded by the ESPN client, with its players
+// converted once when it's loaded rather than on every lookup.
+type espnState struct {
+	*espn.League
+	players map[string]Player
+}
+
+type espnInjuriesJSON map[string]struct {
+	InjuryStatus interface{} `json:"injuryStatus"`
+}
+
+// loadESPNLeague fetches the league with the ESPN client.
+func loadESPNLeague(id string, year int, espnS2 string, swid string) (*espnState, error) {
+	var league espn.League
+	var err error
+	if espnS2 == "" && swid == "" {
+		league, err = espn.NewPublicLeague(espn.GameTypeNfl, id, year)
+	} else {
+		league, err = espn.NewPrivateLeague(espn.GameTypeNfl, id, year, espnS2, swid)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// the client doesn't have a typed field for injury status, since it's
+	// null for healthy players, so it's read back out of the players' JSON
+	injuries := espnInjuriesJSON{}
+	if b, err := json.Marshal(league.Players); err == nil {
+		// a status that isn't a string is treated as healthy
+		_ = json.Unmarshal(b, &injuries)
+	}
+	players := make(map[string]Player, len(league.Players))
+	for playerID, p := range league.Players {
+		id := strconv.FormatInt(playerID, 10)
+		status, _ := injuries[id].InjuryStatus.(string)
+		players[id] = Player{
+			ID:           id,
+			FullName:     p.FullName,
+			Position:     p.Position,
+			NFLTeam:      p.Team,
+			InjuryStatus: injuryStatus(status),
+		}
+	}
+	return &espnState{League: &league, players: players}, nil
+}
+
+// current is the league as of the last refresh.
+func (l *ESPNLeague) current() *espnState {
+	return l.league.Load().(*espnState)
+}
+
+// ID is the ESPN league ID.
+func (l *ESPNLeague) ID() string {
+	return l.current().ID
+}
+
+// Type is always LeagueTypeESPN.
+func (l *ESPNLeague) Type() LeagueType {
+	return LeagueTypeESPN
+}
+
+// Season is the league year.
+func (l *ESPNLeague) Season() string {
+	return fmt.Sprintf("%d", l.current().Year)
+}
+
+// CurrentWeek is the current week as of the last refresh.
+func (l *ESPNLeague) CurrentWeek() (int, error) {
+	return l.current().CurrentWeek, nil
+}
+
+// Teams returns the league's teams keyed by team ID.
+func (l *ESPNLeague) Teams() map[int64]Team {
+	teams := make(map[int64]Team)
+	for _, t := range l.current().Teams {
+		teams[int64(t.ID)] = Team{
+			ID:           int64(t.ID),
+			Name:         t.Name,
+			Abbreviation: t.Abbreviation,
+			OwnerIDs:     t.Owners,
+		}
+	}
+	return teams
+}
+
+// Members returns the league's members keyed by member ID.
+func (l *ESPNLeague) Members() map[string]Member {
+	members := make(map[string]Member)
+	for _, m := range l.current().Members {
+		members[m.ID] = Member{
+			ID:          m.ID,
+			DisplayName: m.DisplayName,
+		}
+	}
+	return members
+}
+
+type espnScheduleJSON struct {
+	Settings struct {
+		ScheduleSettings struct {
+			// MatchupPeriods lists the scoring periods in each matchup
+			// period, keyed by matchup period ID.
+			MatchupPeriods map[string][]int `json:"matchupPeriods"`
+		} `json:"scheduleSettings"`
+	} `json:"settings"`
+	Schedule []struct {
+		ID              int64 `json:"id"`
+		MatchupPeriodID int   `json:"matchupPeriodId"`
+		Home            struct {
+			TeamID          int64    `json:"teamId"`
+			TotalPoints     float64  `json:"totalPoints"`
+			TotalPointsLive *float64 `json:"totalPointsLive"`
+		} `json:"home"`
+		Away struct {
+			TeamID          int64    `json:"teamId"`
+			TotalPoints     float64  `json:"totalPoints"`
+			TotalPointsLive *float64 `json:"totalPointsLive"`
+		} `json:"away"`
+	} `json:"schedule"`
+}
+
+// espnMatchupPeriod finds the matchup period a scoring period is part of.
+// Regular season matchups last one week, but playoff matchups can span
+// several, after which the two IDs no longer line up.
+func espnMatchupPeriod(periods map[string][]int, week int) int {
+	for id, weeks := range periods {
+		for _, w := range weeks {
+			if w != week {
+				continue
+			}
+			if period, err := strconv.Atoi(id); err == nil {
+				return period
+			}
+		}
+	}
+	return week
+}
+
+// Matchups fetches the scores for the given week.  Live scores are used for
+// games in progress.  Matchups spanning several weeks are scored across all
+// of them.
+func (l *ESPNLeague) Matchups(week int) ([]Matchup, error) {
+	res := espnScheduleJSON{}
+	params := url.Values{
+		"view":            {"mMatchupScore", "mScoreboard", "mSettings"},
+		"scoringPeriodId": {fmt.Sprintf("%d", week)},
+	}
+	if err := l.sendRequest(&res, params); err != nil {
+		return nil, err
+	}
+	period := espnMatchupPeriod(res.Settings.ScheduleSettings.MatchupPeriods, week)
+	matchups := make([]Matchup, 0)
+	for _, m := range res.Schedule {
+		if m.MatchupPeriodID != period {
+			continue
+		}
+		matchup := Matchup{
+			ID:         m.ID,
+			Week:       week,
+			HomeTeamID: m.Home.TeamID,
+			AwayTeamID: m.Away.TeamID,
+			HomeScore:  m.Home.TotalPoints,
+			AwayScore:  m.Away.TotalPoints,
+		}
+		if m.Home.TotalPointsLive != nil {
+			matchup.HomeScore = *m.Home.TotalPointsLive
+		}
+		if m.Away.TotalPointsLive != nil {
+			matchup.AwayScore = *m.Away.TotalPointsLive
+		}
+		matchups = append(matchups, matchup)
+	}
+	return matchups, nil
+}
+
+// Projections returns each team's projected score from the ESPN scoreboard.
+func (l *ESPNLeague) Projections() ([]Projection, error) {
+	scoreboard, err := l.current().Scoreboard()
+	if err != nil {
+		return nil, err
+	}
+	projections := make([]Projection, 0, 2*len(scoreboard))
+	for _, m := range scoreboard {
+		projections = append(projections,
+			Projection{MatchupID: m.ID, TeamID: m.HomeTeam.ID, Projection: m.HomeScore},
+			Projection{MatchupID: m.ID, TeamID: m.AwayTeam.ID, Projection: m.AwayScore})
+	}
+	return projections, nil
+}
+
+type espnStandingsJSON struct {
+	Settings struct {
+		ScheduleSettings struct {
+			Divisions []struct {
+				ID   int    `json:"id"`
+				Name string `json:"name"`
+			} `json:"divisions"`
+		} `json:"scheduleSettings"`
+	} `json:"settings"`
+	Teams []struct {
+		ID         int64 `json:"id"`
+		DivisionID int   `json:"divisionId"`
+		Record     struct {
+			Overall struct {
+				Wins          int     `json:"wins"`
+				Losses        int     `json:"losses"`
+				Ties          int     `json:"ties"`
+				PointsFor     float64 `json:"pointsFor"`
+				PointsAgainst float64 `json:"pointsAgainst"`
+				StreakLength  int     `json:"streakLength"`
+				StreakType    string  `json:"streakType"`
+			} `json:"overall"`
+		} `json:"record"`
+	} `json:"teams"`
+}
+
+// Standings fetches each team's record from ESPN.
+func (l *ESPNLeague) Standings() ([]Standing, error) {
+	res := espnStandingsJSON{}
+	if err := l.sendRequest(&res, url.Values{"view": {"mTeam", "mSettings"}}); err != nil {
+		return nil, err
+	}
+	divisions := make(map[int]string)
+	for _, d := range res.Settings.ScheduleSettings.Divisions {
+		divisions[d.ID] = d.Name
+	}
+	standings := make([]Standing, 0, len(res.Teams))
+	for _, t := range res.Teams {
+		r := t.Record.Overall
+		s := Standing{
+			TeamID:        t.ID,
+			Wins:          r.Wins,
+			Losses:        r.Losses,
+			Ties:          r.Ties,
+			PointsFor:     r.PointsFor,
+			PointsAgainst: r.PointsAgainst,
+			Division:      divisions[t.DivisionID],
+		}
+		if r.StreakLength > 0 && r.StreakType != "" {
+			s.Streak = fmt.Sprintf("%s%d", r.StreakType[:1], r.StreakLength)
+		}
+		standings = append(standings, s)
+	}
+	SortStandings(standings)
+	return standings, nil
+}
+
+type espnRosterJSON struct {
+	Teams []struct {
+		ID     int64 `json:"id"`
+		Roster struct {
+			Entries []struct {
+				PlayerID        int64 `json:"playerId"`
+				LineupSlotID    int   `json:"lineupSlotId"`
+				PlayerPoolEntry struct {
+					Player struct {
+						Stats []struct {
+							ScoringPeriodID int     `json:"scoringPeriodId"`
+							StatSourceID    int     `json:"statSourceId"`
+							AppliedTotal    float64 `json:"appliedTotal"`
+						} `json:"stats"`
+					} `json:"player"`
+				} `json:"playerPoolEntry"`
+			} `json:"entries"`
+		} `json:"roster"`
+	} `json:"teams"`
+}
+
+// Lineups fetches each team's lineup for the given week.
+func (l *ESPNLeague) Lineups(week int) (map[int64]Lineup, error) {
+	res := espnRosterJSON{}
+	params := url.Values{
+		"view":            {"mRoster"},
+		"scoringPeriodId": {fmt.Sprintf("%d", week)},
+	}
+	if err := l.sendRequest(&res, params); err != nil {
+		return nil, err
+	}
+	lineups := make(map[int64]Lineup)
+	for _, t := range res.Teams {
+		lineup := Lineup{TeamID: t.ID, Players: make([]LineupPlayer, 0, len(t.Roster.Entries))}
+		for _, e := range t.Roster.Entries {
+			p := LineupPlayer{
+				PlayerID: strconv.FormatInt(e.PlayerID, 10),
+				Slot:     espnSlotNames[e.LineupSlotID],
+				Starter:  e.LineupSlotID != espnSlotBench && e.LineupSlotID != espnSlotIR,
+			}
+			for _, stat := range e.PlayerPoolEntry.Player.Stats {
+				if stat.ScoringPeriodID != week {
+					continue
+				}
+				switch stat.StatSourceID {
+				case espnStatSourceActual:
+					p.Points = stat.AppliedTotal
+				case espnStatSourceProjected:
+					p.Projected = stat.AppliedTotal
+				}
+			}
+			lineup.Players = append(lineup.Players, p)
+		}
+		lineups[t.ID] = lineup
+	}
+	return lineups, nil
+}
+
+type espnPlayerStatJSON struct {
+	SeasonID        int     `json:"seasonId"`
+	ScoringPeriodID int     `json:"scoringPeriodId"`
+	StatSourceID    int     `json:"statSourceId"`
+	AppliedTotal    float64 `json:"appliedTotal"`
+}
+
+type espnPlayerInfoJSON struct {
+	Players []struct {
+		ID     int64 `json:"id"`
+		Player struct {
+			Stats []espnPlayerStatJSON `json:"stats"`
+		} `json:"player"`
+	} `json:"players"`
+}
+
+// espnSeasonPoints finds a season's actual points total in a player's stats.
+func espnSeasonPoints(stats []espnPlayerStatJSON, season int) float64 {
+	for _, stat := range stats {
+		if stat.SeasonID == season && stat.ScoringPeriodID == espnScoringPeriodSeason && stat.StatSourceID == espnStatSourceActual {
+			return stat.AppliedTotal
+		}
+	}
+	return 0
+}
+
+// SeasonPoints fetches a player's points this season in the league's scoring.
+func (l *ESPNLeague) SeasonPoints(playerID string) (float64, error) {
+	id, err := strconv.ParseInt(playerID, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("bad ESPN player ID %q: %w", playerID, err)
+	}
+	filter := fmt.Sprintf(`{"players":{"filterIds":{"value":[%d]}}}`, id)
+	res := espnPlayerInfoJSON{}
+	if err := l.sendFilteredRequest(&res, url.Values{"view": {"kona_player_info"}}, filter); err != nil {
+		return 0, err
+	}
+	for _, p := range res.Players {
+		if p.ID == id {
+			return espnSeasonPoints(p.Player.Stats, int(l.current().Year)), nil
+		}
+	}
+	return 0, nil
+}
+
+// Rosters returns each team's current roster keyed by team ID.
+func (l *ESPNLeague) Rosters() (map[int64]Roster, error) {
+	res := espnRosterJSON{}
+	if err := l.sendRequest(&res, url.Values{"view": {"mRoster"}}); err != nil {
+		return nil, err
+	}
+	rosters := make(map[int64]Roster)
+	for _, t := range res.Teams {
+		r := Roster{TeamID: t.ID, PlayerIDs: make([]string, 0), Starters: make([]string, 0)}
+		for _, e := range t.Roster.Entries {
+			id := strconv.FormatInt(e.PlayerID, 10)
+			r.PlayerIDs = append(r.PlayerIDs, id)
+			if e.LineupSlotID != espnSlotBench && e.LineupSlotID != espnSlotIR {
+				r.Starters = append(r.Starters, id)
+			}
+		}
+		rosters[t.ID] = r
+	}
+	return rosters, nil
+}
+
+// RecentActivity returns recent league transactions, newest first.
+func (l *ESPNLeague) RecentActivity(limit int, offset int) ([]Activity, error) {
+	ra, err := l.current().RecentActivity(limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	activity := make([]Activity, 0, len(ra))
+	for _, a := range ra {
+		actions := make([]ActivityAction, 0, len(a.Actions))
+		for _, action := range a.Actions {
+			actions = append(actions, ActivityAction{
+				Action:   string(action.Action),
+				PlayerID: strconv.FormatInt(action.Player, 10),
+				TeamID:   action.Team,
+			})
+		}
+		activity = append(activity, Activity{
+			ID:        a.ESPNID,
+			Timestamp: a.Timestamp,
+			Actions:   actions,
+		})
+	}
+	return activity, nil
+}
+
+// Player looks up an NFL player by ESPN player ID.
+func (l *ESPNLeague) Player(id string) (Player, bool) {
+	p, ok := l.current().players[id]
+	return p, ok
+}
+
+// Players returns every player the ESPN client loaded for the league.
+func (l *ESPNLeague) Players() []Player {
+	current := l.current()
+	players := make([]Player, 0, len(current.players))
+	for _, p := range current.players {
+		players = append(players, p)
+	}
+	return players
+}
+
+// Refresh reloads league data from ESPN.
+func (l *ESPNLeague) Refresh() error {
+	current := l.current()
+	league, err := loadESPNLeague(current.ID, int(current.Year), l.espnS2, l.swid)
+	if err != nil {
+		return err
+	}
+	l.league.Store(league)
+	return nil
+}
+
+// Config is the config this league was created from.
+func (l *ESPNLeague) Config() LeagueConfigJSON {
+	return l.config
+}
+
+// sendRequest fetches views of the league that the ESPN client doesn't expose.
+func (l *ESPNLeague) sendRequest(v interface{}, params url.Values) error {
+	return l.sendFilteredRequest(v, params, "")
+}
+
+// sendFilteredRequest is sendRequest narrowed by an X-Fantasy-Filter, if
+// filter isn't empty.
+func (l *ESPNLeague) sendFilteredRequest(v interface{}, params url.Values, filter string) error {
+	current := l.current()
+	req, err := http.NewRequest("GET", fmt.Sprintf(espnLeagueURL, current.Year, current.ID), nil)
+	if err != nil {
+		return err
+	}
+	req.URL.RawQuery = params.Encode()
+	if filter != "" {
+		req.Header.Set("X-Fantasy-Filter", filter)
+	}
+	if l.espnS2 != "" || l.swid != "" {
+		req.AddCookie(&http.Cookie{Name: "espn_s2", Value: l.espnS2})
+		req.AddCookie(&http.Cookie{Name: "SWID", Value: l.swid})
+	}
+
+	res, err := l.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("unknown error fetching ESPN views %v, status code: %d", params["view"], res.StatusCode)
+	}
+
+	return json.NewDecoder(res.Body).Decode(v)
+}
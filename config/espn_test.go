@@ -0,0 +1,49 @@
+package config
+
+import "testing"
+
+func TestESPNMatchupPeriod(t *testing.T) {
+	// two-week playoff rounds after a 14 week regular season
+	periods := map[string][]int{
+		"1":  {1},
+		"14": {14},
+		"15": {15, 16},
+		"16": {17, 18},
+	}
+	tests := []struct {
+		week int
+		want int
+	}{
+		{1, 1},
+		{14, 14},
+		{15, 15},
+		{16, 15},
+		{17, 16},
+		{18, 16},
+		// weeks missing from the settings match their own period
+		{5, 5},
+	}
+	for _, tt := range tests {
+		if got := espnMatchupPeriod(periods, tt.week); got != tt.want {
+			t.Errorf("espnMatchupPeriod(%d) = %d, want %d", tt.week, got, tt.want)
+		}
+	}
+	if got := espnMatchupPeriod(nil, 3); got != 3 {
+		t.Errorf("espnMatchupPeriod(nil, 3) = %d, want 3", got)
+	}
+}
+
+func TestESPNSeasonPoints(t *testing.T) {
+	stats := []espnPlayerStatJSON{
+		{SeasonID: 2022, ScoringPeriodID: 5, StatSourceID: espnStatSourceActual, AppliedTotal: 21.5},
+		{SeasonID: 2022, ScoringPeriodID: 0, StatSourceID: espnStatSourceProjected, AppliedTotal: 250},
+		{SeasonID: 2021, ScoringPeriodID: 0, StatSourceID: espnStatSourceActual, AppliedTotal: 301.2},
+		{SeasonID: 2022, ScoringPeriodID: 0, StatSourceID: espnStatSourceActual, AppliedTotal: 98.4},
+	}
+	if got := espnSeasonPoints(stats, 2022); got != 98.4 {
+		t.Errorf("espnSeasonPoints(2022) = %g, want 98.4", got)
+	}
+	if got := espnSeasonPoints(stats, 2023); got != 0 {
+		t.Errorf("espnSeasonPoints(2023) = %g, want 0", got)
+	}
+}
@@ -0,0 +1,314 @@
+package config
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	// Windows are in a named timezone, and the bot's container has no
+	// zoneinfo.
+	_ "time/tzdata"
+)
+
+// GameTimeSchedule is the update_scores schedule that polls on the
+// GameTimeConfigJSON windows.
+const GameTimeSchedule = "game_time"
+
+const nflStateCacheTime = time.Hour
+
+// gameLength is how long after kickoff a game is polled for, long enough for
+// overtime and delays.
+const gameLength = 4 * time.Hour
+
+// GameWindowJSON is a time of week when NFL games are usually live.
+type GameWindowJSON struct {
+	// Days are the days the window starts on, e.g. ["sun"].
+	Days []string `json:"days"`
+	// Start and End are times like "13:00".  An End before Start runs past
+	// midnight.
+	Start string `json:"start"`
+	End   string `json:"end"`
+	// Interval is how often to poll during the window, e.g. "2m".
+	Interval string `json:"interval"`
+}
+
+// GameTimeConfigJSON polls often while games are live and rarely otherwise.
+// During the regular season it polls from each kickoff on the NFL scoreboard
+// until the game's likely over, using Windows only if the scoreboard can't be
+// fetched.
+type GameTimeConfigJSON struct {
+	// GameInterval is how often to poll while a game on the scoreboard is
+	// live, defaulting to 2 minutes.
+	GameInterval string `json:"game_interval"`
+	// Timezone is the timezone of the windows, defaulting to America/New_York.
+	Timezone string `json:"timezone"`
+	// Windows default to Thursday, Sunday and Monday games every 2 minutes
+	// and Saturday games every 5 minutes.
+	Windows []GameWindowJSON `json:"windows"`
+	// IdleInterval is how often to poll during the season outside of a
+	// window, defaulting to an hour.
+	IdleInterval string `json:"idle_interval"`
+	// OffseasonInterval is how often to poll when Sleeper's NFL state isn't
+	// the regular season or playoffs, defaulting to a day.
+	OffseasonInterval string `json:"offseason_interval"`
+}
+
+var defaultGameWindows = []GameWindowJSON{
+	{Days: []string{"thu", "mon"}, Start: "19:00", End: "00:30", Interval: "2m"},
+	{Days: []string{"sun"}, Start: "09:00", End: "00:30", Interval: "2m"},
+	{Days: []string{"sat"}, Start: "16:00", End: "00:30", Interval: "5m"},
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+type gameWindow struct {
+	days map[time.Weekday]bool
+	// start and end are minutes after midnight
+	start, end int
+	interval   time.Duration
+}
+
+// contains reports whether t, in the schedule's timezone, is in the window.
+func (w gameWindow) contains(t time.Time) bool {
+	minute := t.Hour()*60 + t.Minute()
+	if w.end > w.start {
+		return w.days[t.Weekday()] && minute >= w.start && minute < w.end
+	}
+	// the window runs past midnight
+	return (w.days[t.Weekday()] && minute >= w.start) || (w.days[(t.Weekday()+6)%7] && minute < w.end)
+}
+
+type gameTimeSchedule struct {
+	loc       *time.Location
+	windows   []gameWindow
+	game      time.Duration
+	idle      time.Duration
+	offseason time.Duration
+
+	httpClient *http.Client
+	nflGames   func(season string, week int) (NFLWeek, error)
+	mu         sync.Mutex
+	inSeason   bool
+	// kickoffs are this week's kickoff times, or nil to use the windows.
+	kickoffs []time.Time
+	checked  time.Time
+}
+
+// NewGameTimeSchedule creates the schedule described by c.
+func NewGameTimeSchedule(c GameTimeConfigJSON) (Schedule, error) {
+	s := &gameTimeSchedule{
+		game:       2 * time.Minute,
+		idle:       time.Hour,
+		offseason:  24 * time.Hour,
+		httpClient: &http.Client{Timeout: time.Minute},
+		nflGames:   NFLGames,
+	}
+
+	tz := c.Timezone
+	if tz == "" {
+		tz = defaultTimezone
+	}
+	var err error
+	s.loc, err = time.LoadLocation(tz)
+	if err != nil {
+		return nil, fmt.Errorf("timezone: %w", err)
+	}
+
+	if c.GameInterval != "" {
+		if s.game, err = parseInterval(c.GameInterval); err != nil {
+			return nil, fmt.Errorf("game_interval: %w", err)
+		}
+	}
+	if c.IdleInterval != "" {
+		if s.idle, err = parseInterval(c.IdleInterval); err != nil {
+			return nil, fmt.Errorf("idle_interval: %w", err)
+		}
+	}
+	if c.OffseasonInterval != "" {
+		if s.offseason, err = parseInterval(c.OffseasonInterval); err != nil {
+			return nil, fmt.Errorf("offseason_interval: %w", err)
+		}
+	}
+
+	windows := c.Windows
+	if len(windows) == 0 {
+		windows = defaultGameWindows
+	}
+	for i, wc := range windows {
+		w := gameWindow{days: make(map[time.Weekday]bool)}
+		for _, d := range wc.Days {
+			wd, ok := weekdays[strings.ToLower(d)]
+			if !ok {
+				return nil, fmt.Errorf("windows[%d]: unknown day %q", i, d)
+			}
+			w.days[wd] = true
+		}
+		if len(w.days) == 0 {
+			return nil, fmt.Errorf("windows[%d]: days is required", i)
+		}
+		if w.start, err = parseTimeOfDay(wc.Start); err != nil {
+			return nil, fmt.Errorf("windows[%d]: start: %w", i, err)
+		}
+		if w.end, err = parseTimeOfDay(wc.End); err != nil {
+			return nil, fmt.Errorf("windows[%d]: end: %w", i, err)
+		}
+		if w.start == w.end {
+			return nil, fmt.Errorf("windows[%d]: start and end are the same", i)
+		}
+		if w.interval, err = parseInterval(wc.Interval); err != nil {
+			return nil, fmt.Errorf("windows[%d]: interval: %w", i, err)
+		}
+		s.windows = append(s.windows, w)
+	}
+	return s, nil
+}
+
+func parseInterval(s string) (time.Duration, error) {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("interval %s must be positive", s)
+	}
+	return d, nil
+}
+
+// parseTimeOfDay parses "HH:MM" as minutes after midnight.
+func parseTimeOfDay(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a time like 13:00", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Next polls at the game interval while a game on the scoreboard is live, or
+// at the shortest interval of any window t is in if there's no scoreboard.
+// Otherwise it polls at the idle interval, but no later than the next kickoff
+// or window.
+func (s *gameTimeSchedule) Next(t time.Time) time.Time {
+	inSeason, kickoffs := s.nflState()
+	if !inSeason {
+		return t.Add(s.offseason)
+	}
+	if kickoffs != nil {
+		return s.nextFromKickoffs(t, kickoffs)
+	}
+
+	local := t.In(s.loc)
+	var interval time.Duration
+	for _, w := range s.windows {
+		if w.contains(local) && (interval == 0 || w.interval < interval) {
+			interval = w.interval
+		}
+	}
+	if interval > 0 {
+		return t.Add(interval)
+	}
+
+	next := t.Add(s.idle)
+	if start := s.nextWindowStart(local); !start.IsZero() && start.Before(next) {
+		return start
+	}
+	return next
+}
+
+// nextFromKickoffs is Next for a week with a scoreboard.
+func (s *gameTimeSchedule) nextFromKickoffs(t time.Time, kickoffs []time.Time) time.Time {
+	next := t.Add(s.idle)
+	for _, k := range kickoffs {
+		if !t.Before(k) && t.Before(k.Add(gameLength)) {
+			return t.Add(s.game)
+		}
+		if k.After(t) && k.Before(next) {
+			next = k
+		}
+	}
+	return next
+}
+
+// nextWindowStart returns when the next window after t starts.
+func (s *gameTimeSchedule) nextWindowStart(t time.Time) time.Time {
+	var next time.Time
+	for d := 0; d <= 7; d++ {
+		day := time.Date(t.Year(), t.Month(), t.Day()+d, 0, 0, 0, 0, s.loc)
+		for _, w := range s.windows {
+			if !w.days[day.Weekday()] {
+				continue
+			}
+			start := time.Date(day.Year(), day.Month(), day.Day(), w.start/60, w.start%60, 0, 0, s.loc)
+			if start.After(t) && (next.IsZero() || start.Before(next)) {
+				next = start
+			}
+		}
+	}
+	return next
+}
+
+// sleeperNFLStateJSON is the part of Sleeper's NFL state used here.
+type sleeperNFLStateJSON struct {
+	// SeasonType is "pre", "regular", "post" or "off".
+	SeasonType string `json:"season_type"`
+	Season     string `json:"season"`
+	Week       int    `json:"week"`
+}
+
+// nflState reports whether fantasy games are being played, according to
+// Sleeper's NFL state, and the kickoffs this week if it's the regular season.
+// If Sleeper can't be reached it assumes games are being played until the
+// next check, so scores aren't missed.
+func (s *gameTimeSchedule) nflState() (bool, []time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if time.Since(s.checked) < nflStateCacheTime {
+		return s.inSeason, s.kickoffs
+	}
+
+	state := sleeperNFLStateJSON{}
+	s.checked = time.Now()
+	s.kickoffs = nil
+	if err := sleeperGet(s.httpClient, "/state/nfl", &state); err != nil {
+		log.Printf("error getting NFL state, assuming in season: %s", err)
+		s.inSeason = true
+		return s.inSeason, s.kickoffs
+	}
+	s.inSeason = state.SeasonType == "regular" || state.SeasonType == "post"
+	if state.SeasonType == "regular" {
+		s.kickoffs = s.weekKickoffs(state.Season, state.Week)
+	}
+	return s.inSeason, s.kickoffs
+}
+
+// weekKickoffs returns the kickoffs in a week of the regular season, or nil
+// if the scoreboard can't be fetched.
+func (s *gameTimeSchedule) weekKickoffs(season string, week int) []time.Time {
+	games, err := s.nflGames(season, week)
+	if err != nil {
+		log.Printf("error getting NFL games for %s week %d, using game time windows: %s", season, week, err)
+		return nil
+	}
+	return games.kickoffs()
+}
+
+// ScoresSchedule returns the update_scores schedule, or nil if the bot
+// shouldn't run it.
+func (c JobsConfigJSON) ScoresSchedule() (Schedule, error) {
+	switch c.UpdateScores {
+	case "":
+		return nil, nil
+	case GameTimeSchedule:
+		return NewGameTimeSchedule(c.GameTimeConfig)
+	}
+	return c.Schedule(c.UpdateScores)
+}
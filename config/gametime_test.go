@@ -0,0 +1,191 @@
+package config
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// newTestGameTimeSchedule creates a game time schedule that doesn't check
+// Sleeper for whether it's the season.
+func newTestGameTimeSchedule(t *testing.T, c GameTimeConfigJSON, inSeason bool) *gameTimeSchedule {
+	t.Helper()
+	s, err := NewGameTimeSchedule(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gs := s.(*gameTimeSchedule)
+	gs.inSeason = inSeason
+	gs.checked = time.Now()
+	return gs
+}
+
+func TestGameWindowContains(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	evening := gameWindow{days: map[time.Weekday]bool{time.Thursday: true}, start: 19 * 60, end: 30}
+	afternoon := gameWindow{days: map[time.Weekday]bool{time.Sunday: true}, start: 13 * 60, end: 17 * 60}
+	tests := []struct {
+		name   string
+		window gameWindow
+		t      time.Time
+		want   bool
+	}{
+		{"before start", evening, time.Date(2022, 10, 6, 18, 59, 0, 0, newYork), false},
+		{"at start", evening, time.Date(2022, 10, 6, 19, 0, 0, 0, newYork), true},
+		{"past midnight", evening, time.Date(2022, 10, 7, 0, 29, 0, 0, newYork), true},
+		{"at end", evening, time.Date(2022, 10, 7, 0, 30, 0, 0, newYork), false},
+		{"past midnight on the wrong day", evening, time.Date(2022, 10, 6, 0, 15, 0, 0, newYork), false},
+		{"wrong day", evening, time.Date(2022, 10, 5, 20, 0, 0, 0, newYork), false},
+		{"during", afternoon, time.Date(2022, 10, 9, 16, 0, 0, 0, newYork), true},
+		{"after", afternoon, time.Date(2022, 10, 9, 17, 0, 0, 0, newYork), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.window.contains(tt.t); got != tt.want {
+				t.Errorf("contains(%s) = %t, want %t", tt.t, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGameTimeScheduleNext(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := GameTimeConfigJSON{
+		Windows: []GameWindowJSON{
+			{Days: []string{"sun"}, Start: "13:00", End: "20:00", Interval: "5m"},
+			{Days: []string{"Sun"}, Start: "16:00", End: "17:00", Interval: "2m"},
+			{Days: []string{"mon"}, Start: "20:00", End: "00:30", Interval: "3m"},
+		},
+		IdleInterval:      "1h",
+		OffseasonInterval: "12h",
+	}
+	at := func(day int, hour int, minute int) time.Time {
+		return time.Date(2022, 10, day, hour, minute, 0, 0, newYork)
+	}
+	tests := []struct {
+		name     string
+		inSeason bool
+		t        time.Time
+		want     time.Time
+	}{
+		{"in a window", true, at(9, 14, 0), at(9, 14, 5)},
+		{"in overlapping windows", true, at(9, 16, 30), at(9, 16, 32)},
+		{"past midnight", true, at(11, 0, 10), at(11, 0, 13)},
+		{"idle", true, at(12, 9, 0), at(12, 10, 0)},
+		{"idle until a window starts", true, at(9, 12, 30), at(9, 13, 0)},
+		{"idle the night before a window", true, at(15, 23, 0), at(16, 0, 0)},
+		{"offseason", false, at(9, 14, 0), at(10, 2, 0)},
+		// times in other timezones are compared in the schedule's
+		{"in a window from UTC", true, at(9, 14, 0).UTC(), at(9, 14, 5)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestGameTimeSchedule(t, c, tt.inSeason)
+			if got := s.Next(tt.t); !got.Equal(tt.want) {
+				t.Errorf("Next(%s) = %s, want %s", tt.t, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGameTimeScheduleNextKickoffs(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(day int, hour int, minute int) time.Time {
+		return time.Date(2022, 10, day, hour, minute, 0, 0, newYork)
+	}
+	c := GameTimeConfigJSON{
+		GameInterval: "3m",
+		// the scoreboard's used instead
+		Windows:      []GameWindowJSON{{Days: []string{"sun"}, Start: "09:00", End: "00:30", Interval: "1m"}},
+		IdleInterval: "1h",
+	}
+	kickoffs := []time.Time{at(9, 9, 30), at(9, 13, 0), at(9, 16, 25), at(10, 20, 15)}
+	tests := []struct {
+		name string
+		t    time.Time
+		want time.Time
+	}{
+		{"at kickoff", at(9, 9, 30), at(9, 9, 33)},
+		{"during a game", at(9, 12, 0), at(9, 12, 3)},
+		{"in overtime", at(11, 0, 0), at(11, 0, 3)},
+		{"idle", at(10, 9, 0), at(10, 10, 0)},
+		{"idle until kickoff", at(10, 19, 45), at(10, 20, 15)},
+		{"after the last game", at(11, 0, 15), at(11, 1, 15)},
+		{"in a window without a game", at(8, 10, 0), at(8, 11, 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestGameTimeSchedule(t, c, true)
+			s.kickoffs = kickoffs
+			if got := s.Next(tt.t); !got.Equal(tt.want) {
+				t.Errorf("Next(%s) = %s, want %s", tt.t, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGameTimeScheduleWeekKickoffs(t *testing.T) {
+	sunday := time.Date(2022, 10, 9, 17, 0, 0, 0, time.UTC)
+	monday := time.Date(2022, 10, 11, 0, 15, 0, 0, time.UTC)
+	s := newTestGameTimeSchedule(t, GameTimeConfigJSON{}, true)
+	s.nflGames = func(season string, week int) (NFLWeek, error) {
+		if season != "2022" || week != 5 {
+			t.Errorf("nflGames(%q, %d), want 2022 week 5", season, week)
+		}
+		return NFLWeek{
+			"NYG": {Kickoff: monday},
+			"DAL": {Kickoff: monday},
+			"BUF": {Kickoff: sunday},
+			"PIT": {Kickoff: sunday},
+			"TBD": {},
+		}, nil
+	}
+	got := s.weekKickoffs("2022", 5)
+	if len(got) != 2 || !got[0].Equal(sunday) || !got[1].Equal(monday) {
+		t.Errorf("weekKickoffs() = %v, want [%s %s]", got, sunday, monday)
+	}
+
+	s.nflGames = func(season string, week int) (NFLWeek, error) {
+		return nil, errors.New("scoreboard is down")
+	}
+	if got := s.weekKickoffs("2022", 5); got != nil {
+		t.Errorf("weekKickoffs() with a failing scoreboard = %v, want nil for the windows", got)
+	}
+}
+
+func TestNewGameTimeScheduleErrors(t *testing.T) {
+	window := func(w GameWindowJSON) GameTimeConfigJSON {
+		return GameTimeConfigJSON{Windows: []GameWindowJSON{w}}
+	}
+	tests := []struct {
+		name string
+		c    GameTimeConfigJSON
+	}{
+		{"bad timezone", GameTimeConfigJSON{Timezone: "Nowhere/Special"}},
+		{"bad game interval", GameTimeConfigJSON{GameInterval: "often"}},
+		{"bad idle interval", GameTimeConfigJSON{IdleInterval: "0s"}},
+		{"bad offseason interval", GameTimeConfigJSON{OffseasonInterval: "soon"}},
+		{"no days", window(GameWindowJSON{Start: "13:00", End: "14:00", Interval: "1m"})},
+		{"unknown day", window(GameWindowJSON{Days: []string{"someday"}, Start: "13:00", End: "14:00", Interval: "1m"})},
+		{"bad start", window(GameWindowJSON{Days: []string{"sun"}, Start: "1pm", End: "14:00", Interval: "1m"})},
+		{"bad end", window(GameWindowJSON{Days: []string{"sun"}, Start: "13:00", End: "25:00", Interval: "1m"})},
+		{"empty window", window(GameWindowJSON{Days: []string{"sun"}, Start: "13:00", End: "13:00", Interval: "1m"})},
+		{"bad interval", window(GameWindowJSON{Days: []string{"sun"}, Start: "13:00", End: "14:00", Interval: "-1m"})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewGameTimeSchedule(tt.c); err == nil {
+				t.Errorf("NewGameTimeSchedule(%+v) succeeded, want an error", tt.c)
+			}
+		})
+	}
+}
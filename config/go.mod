@@ -4,8 +4,8 @@ go 1.19
 
 require (
 	cloud.google.com/go/storage v1.23.0
-	github.com/craigatron/espn-fantasy-go v0.0.2-0.20220731182059-c6130f269bb2
-	github.com/craigatron/sleeper-go v0.0.0-20220716033712-ca98b02ac113
+	github.com/craigatron/espn-fantasy-go v0.0.2-0.20220908003037-8c46118e0ace
+	github.com/craigatron/sleeper-go v0.0.0-20220907013444-753ab69ad51f
 )
 
 require (
@@ -0,0 +1,222 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// League is a fantasy football league, independent of the site hosting it.
+type League interface {
+	// ID is the league ID on the hosting site.
+	ID() string
+	// Type is the site hosting the league.
+	Type() LeagueType
+	// Season is the NFL season the league is playing, e.g. "2022".
+	Season() string
+	// CurrentWeek is the current scoring week of the season.
+	CurrentWeek() (int, error)
+	// Teams returns the league's teams keyed by team ID.
+	Teams() map[int64]Team
+	// Members returns the league's members keyed by member ID.
+	Members() map[string]Member
+	// Matchups returns the head-to-head matchups for the given week.
+	Matchups(week int) ([]Matchup, error)
+	// Projections returns each team's projected score for the current week.
+	Projections() ([]Projection, error)
+	// Standings returns each team's season record.
+	Standings() ([]Standing, error)
+	// Lineups returns each team's lineup and player scores for the given
+	// week, keyed by team ID.
+	Lineups(week int) (map[int64]Lineup, error)
+	// Rosters returns each team's current roster keyed by team ID.
+	Rosters() (map[int64]Roster, error)
+	// RecentActivity returns recent league transactions, newest first.
+	RecentActivity(limit int, offset int) ([]Activity, error)
+	// Player looks up an NFL player by ID.
+	Player(id string) (Player, bool)
+	// Players returns every NFL player the league can look up.
+	Players() []Player
+	// SeasonPoints returns an NFL player's fantasy points in the league's
+	// scoring this season, whether or not they were on a roster.
+	SeasonPoints(playerID string) (float64, error)
+	// Refresh reloads cached league data from the hosting site.
+	Refresh() error
+	// Config is the config this league was created from.
+	Config() LeagueConfigJSON
+}
+
+// Team is a fantasy team in a league.
+type Team struct {
+	ID           int64
+	Name         string
+	Abbreviation string
+	OwnerIDs     []string
+}
+
+// Member is a user who owns a team in a league.
+type Member struct {
+	ID          string
+	DisplayName string
+}
+
+// Matchup is a head-to-head game between two teams.
+type Matchup struct {
+	ID         int64
+	Week       int
+	HomeTeamID int64
+	AwayTeamID int64
+	HomeScore  float64
+	AwayScore  float64
+}
+
+// Projection is a team's projected score in a matchup.
+type Projection struct {
+	MatchupID  int64
+	TeamID     int64
+	Projection float64
+}
+
+// Standing is a team's season record.
+type Standing struct {
+	TeamID        int64
+	Wins          int
+	Losses        int
+	Ties          int
+	PointsFor     float64
+	PointsAgainst float64
+	// Streak is the current win or loss streak, e.g. "W3".
+	Streak   string
+	Division string
+	// DivisionRank is the team's 1-based rank within its division.
+	DivisionRank int
+}
+
+// WinPercentage counts ties as half a win.
+func (s Standing) WinPercentage() float64 {
+	games := s.Wins + s.Losses + s.Ties
+	if games == 0 {
+		return 0
+	}
+	return (float64(s.Wins) + float64(s.Ties)/2) / float64(games)
+}
+
+// SortStandings orders standings by win percentage then points for, and
+// fills in each team's DivisionRank.
+func SortStandings(standings []Standing) {
+	sort.SliceStable(standings, func(i, j int) bool {
+		if standings[i].WinPercentage() != standings[j].WinPercentage() {
+			return standings[i].WinPercentage() > standings[j].WinPercentage()
+		}
+		return standings[i].PointsFor > standings[j].PointsFor
+	})
+	divisionCounts := make(map[string]int)
+	for i := range standings {
+		divisionCounts[standings[i].Division]++
+		standings[i].DivisionRank = divisionCounts[standings[i].Division]
+	}
+}
+
+// LineupPlayer is a player in a team's lineup for a week.
+type LineupPlayer struct {
+	PlayerID string
+	// Slot is the lineup position the player fills, e.g. "FLEX" or "BN".
+	Slot      string
+	Starter   bool
+	Points    float64
+	Projected float64
+}
+
+// Lineup is a team's starters and bench for a week.
+type Lineup struct {
+	TeamID  int64
+	Players []LineupPlayer
+}
+
+// Roster is the set of players on a team.
+type Roster struct {
+	TeamID    int64
+	PlayerIDs []string
+	Starters  []string
+}
+
+// Player is an NFL player.
+type Player struct {
+	ID       string
+	FullName string
+	Position string
+	NFLTeam  string
+	// InjuryStatus is e.g. "QUESTIONABLE" or "Out", or empty if the player
+	// is healthy.
+	InjuryStatus string
+}
+
+// healthyStatuses are injury statuses that mean the player isn't injured.
+var healthyStatuses = map[string]bool{"": true, "ACTIVE": true, "NORMAL": true}
+
+// injuryStatus normalizes a player's injury status, which is empty for
+// healthy players.
+func injuryStatus(status string) string {
+	if healthyStatuses[status] {
+		return ""
+	}
+	return status
+}
+
+// Actions for Sleeper activity.  ESPN activity uses the action names from the
+// ESPN client.
+const (
+	ActionAdded       = "ADDED"
+	ActionDropped     = "DROPPED"
+	ActionWaiverAdded = "WAIVER ADDED"
+	ActionTraded      = "TRADED"
+)
+
+// ActivityAction is a single move within a transaction.  Most actions move a
+// player, but a trade can also move a draft pick or FAAB budget.
+type ActivityAction struct {
+	Action   string `firestore:"Action"`
+	PlayerID string `firestore:"Player"`
+	TeamID   int64  `firestore:"Team"`
+	// Pick describes a traded draft pick, e.g. "2023 round 1".
+	Pick string `firestore:"Pick,omitempty"`
+	// FAAB is a waiver bid, or budget moved in a trade.
+	FAAB int `firestore:"FAAB,omitempty"`
+}
+
+// Activity is a league transaction, e.g. a waiver claim or trade.
+type Activity struct {
+	ID        string
+	Timestamp int64
+	Actions   []ActivityAction
+}
+
+// FormatAction describes a single transaction action, e.g.
+// "Team Name WAIVER ADDED Player Name (RB, SEA) for $12".
+func FormatAction(l League, teams map[int64]Team, action ActivityAction) string {
+	var what string
+	if action.Pick != "" {
+		what = action.Pick + " pick"
+	} else if action.PlayerID == "" {
+		what = "FAAB"
+	} else if player, ok := l.Player(action.PlayerID); ok {
+		what = fmt.Sprintf("%s (%s, %s)", player.FullName, player.Position, player.NFLTeam)
+	} else {
+		what = fmt.Sprintf("player %s", action.PlayerID)
+	}
+	s := fmt.Sprintf("%s %s %s", teams[action.TeamID].Name, action.Action, what)
+	if action.FAAB > 0 {
+		s += fmt.Sprintf(" for $%d", action.FAAB)
+	}
+	return s
+}
+
+// LeagueKey is the Firestore document path for the league.
+func LeagueKey(l League) string {
+	return fmt.Sprintf("leagues/%s-%s", strings.ToLower(l.Type().String()), l.ID())
+}
+
+// LeagueYearKey is the Firestore document path for the league's current season.
+func LeagueYearKey(l League) string {
+	return fmt.Sprintf("%s/years/%s", LeagueKey(l), l.Season())
+}
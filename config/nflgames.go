@@ -0,0 +1,163 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// nflScoreboardURL is ESPN's public NFL scoreboard, which has the status of
+// every game no matter which site hosts the league.
+const nflScoreboardURL = "https://site.api.espn.com/apis/site/v2/sports/football/nfl/scoreboard"
+
+// nflKickoffLayout is the format of the scoreboard's kickoff times, which
+// leave out seconds.
+const nflKickoffLayout = "2006-01-02T15:04Z07:00"
+
+// nflQuarterSeconds is the length of a quarter of regulation.
+const nflQuarterSeconds = 15 * 60
+
+// nflTeamAliases maps team abbreviations the fantasy sites use to the
+// scoreboard's.
+var nflTeamAliases = map[string]string{
+	"WAS": "WSH",
+}
+
+var nflHTTPClient = &http.Client{Timeout: time.Minute}
+
+// NFLGame is the status of an NFL game.
+type NFLGame struct {
+	// Kickoff is when the game is scheduled to start, or zero if the
+	// scoreboard didn't say.
+	Kickoff time.Time
+	Started bool
+	Final   bool
+	// Period is the quarter being played, with overtime as 5.
+	Period int
+	// Clock is the seconds left in the period.
+	Clock float64
+}
+
+// Remaining is the fraction of regulation left to play, 1 before kickoff and
+// 0 once the game is over or in overtime.
+func (g NFLGame) Remaining() float64 {
+	switch {
+	case !g.Started:
+		return 1
+	case g.Final || g.Period > 4:
+		return 0
+	}
+	left := float64(4-g.Period)*nflQuarterSeconds + g.Clock
+	if left < 0 {
+		return 0
+	}
+	if left > 4*nflQuarterSeconds {
+		return 1
+	}
+	return left / (4 * nflQuarterSeconds)
+}
+
+// NFLWeek is the status of every game in a week, keyed by the scoreboard's
+// abbreviation of each team playing.
+type NFLWeek map[string]NFLGame
+
+// Game looks up a team's game by its abbreviation on any site.  Teams on bye
+// don't have one.
+func (w NFLWeek) Game(team string) (NFLGame, bool) {
+	team = strings.ToUpper(team)
+	if alias, ok := nflTeamAliases[team]; ok {
+		team = alias
+	}
+	g, ok := w[team]
+	return g, ok
+}
+
+// Final is whether every game in the week is over.  A week without any games
+// isn't final.
+func (w NFLWeek) Final() bool {
+	for _, g := range w {
+		if !g.Final {
+			return false
+		}
+	}
+	return len(w) > 0
+}
+
+// kickoffs returns the times games kick off in the week, in order.  Games
+// kicking off together share one.
+func (w NFLWeek) kickoffs() []time.Time {
+	seen := make(map[time.Time]bool)
+	kickoffs := make([]time.Time, 0)
+	for _, g := range w {
+		if g.Kickoff.IsZero() || seen[g.Kickoff] {
+			continue
+		}
+		seen[g.Kickoff] = true
+		kickoffs = append(kickoffs, g.Kickoff)
+	}
+	sort.Slice(kickoffs, func(i, j int) bool { return kickoffs[i].Before(kickoffs[j]) })
+	return kickoffs
+}
+
+type nflScoreboardJSON struct {
+	Events []struct {
+		// Date is the kickoff time, like "2022-10-09T17:00Z".
+		Date         string `json:"date"`
+		Competitions []struct {
+			Competitors []struct {
+				Team struct {
+					Abbreviation string `json:"abbreviation"`
+				} `json:"team"`
+			} `json:"competitors"`
+		} `json:"competitions"`
+		Status struct {
+			Clock  float64 `json:"clock"`
+			Period int     `json:"period"`
+			Type   struct {
+				// State is "pre", "in" or "post".
+				State     string `json:"state"`
+				Completed bool   `json:"completed"`
+			} `json:"type"`
+		} `json:"status"`
+	} `json:"events"`
+}
+
+// NFLGames returns the status of the games in a week of the NFL regular
+// season.
+func NFLGames(season string, week int) (NFLWeek, error) {
+	u := fmt.Sprintf("%s?seasontype=2&week=%d&dates=%s", nflScoreboardURL, week, season)
+	res, err := nflHTTPClient.Get(u)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusBadRequest {
+		return nil, fmt.Errorf("unknown error fetching NFL scoreboard for %s week %d, status code: %d", season, week, res.StatusCode)
+	}
+
+	scoreboard := nflScoreboardJSON{}
+	if err := json.NewDecoder(res.Body).Decode(&scoreboard); err != nil {
+		return nil, err
+	}
+	games := make(NFLWeek)
+	for _, e := range scoreboard.Events {
+		g := NFLGame{
+			Started: e.Status.Type.State != "pre",
+			Final:   e.Status.Type.Completed || e.Status.Type.State == "post",
+			Period:  e.Status.Period,
+			Clock:   e.Status.Clock,
+		}
+		if kickoff, err := time.Parse(nflKickoffLayout, e.Date); err == nil {
+			g.Kickoff = kickoff
+		}
+		for _, c := range e.Competitions {
+			for _, team := range c.Competitors {
+				games[strings.ToUpper(team.Team.Abbreviation)] = g
+			}
+		}
+	}
+	return games, nil
+}
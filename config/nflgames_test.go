@@ -0,0 +1,70 @@
+package config
+
+import "testing"
+
+func TestNFLGameRemaining(t *testing.T) {
+	tests := []struct {
+		name string
+		game NFLGame
+		want float64
+	}{
+		{"not started", NFLGame{}, 1},
+		{"kickoff", NFLGame{Started: true, Period: 1, Clock: 900}, 1},
+		{"halftime", NFLGame{Started: true, Period: 2, Clock: 0}, 0.5},
+		{"fourth quarter", NFLGame{Started: true, Period: 4, Clock: 360}, 0.1},
+		{"overtime", NFLGame{Started: true, Period: 5, Clock: 300}, 0},
+		{"final", NFLGame{Started: true, Final: true, Period: 4}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.game.Remaining(); got != tt.want {
+				t.Errorf("%+v.Remaining() = %g, want %g", tt.game, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNFLWeekGame(t *testing.T) {
+	games := NFLWeek{
+		"WSH": {Started: true, Period: 2},
+		"JAX": {},
+	}
+	tests := []struct {
+		team string
+		want bool
+	}{
+		{"WSH", true},
+		// Sleeper's abbreviation
+		{"WAS", true},
+		// ESPN fantasy's
+		{"Wsh", true},
+		{"Jax", true},
+		{"BUF", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if _, got := games.Game(tt.team); got != tt.want {
+			t.Errorf("Game(%q) found = %t, want %t", tt.team, got, tt.want)
+		}
+	}
+}
+
+func TestNFLWeekFinal(t *testing.T) {
+	tests := []struct {
+		name  string
+		games NFLWeek
+		want  bool
+	}{
+		{"no games", NFLWeek{}, false},
+		{"not started", NFLWeek{"BUF": {}, "KC": {Started: true, Final: true}}, false},
+		{"in progress", NFLWeek{"BUF": {Started: true, Period: 4}, "KC": {Started: true, Final: true}}, false},
+		{"all over", NFLWeek{"BUF": {Started: true, Final: true}, "KC": {Started: true, Final: true}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.games.Final(); got != tt.want {
+				t.Errorf("Final() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
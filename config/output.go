@@ -0,0 +1,57 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// OutputConfigJSON selects where update-scores writes its chart pages and the
+// public URL they're served from.
+type OutputConfigJSON struct {
+	// Type is "gcs", "local" or "s3".  Leaving it empty writes no pages.
+	Type string `json:"type"`
+	// Bucket is the GCS or S3 bucket.
+	Bucket string `json:"bucket"`
+	// Path is the local directory.
+	Path string `json:"path"`
+	// Endpoint is the S3-compatible endpoint, e.g.
+	// "https://s3.us-east-1.amazonaws.com".  Objects are addressed path
+	// style, as endpoint/bucket/object.
+	Endpoint        string `json:"endpoint"`
+	Region          string `json:"region"`
+	AccessKeyID     string `json:"access_key_id"`
+	SecretAccessKey string `json:"secret_access_key"`
+	// BaseURL is the public URL the output root is served at.  It defaults
+	// to the bucket's public URL for gcs and s3, and to nothing for local,
+	// in which case pages aren't linked.
+	BaseURL string `json:"base_url"`
+}
+
+// PublicURL is the URL the output file name is served at, or "" if there's
+// no public URL configured.
+func (c OutputConfigJSON) PublicURL(name string) string {
+	base := c.BaseURL
+	if base == "" {
+		switch c.Type {
+		case "gcs":
+			base = fmt.Sprintf("https://storage.googleapis.com/%s", c.Bucket)
+		case "s3":
+			base = fmt.Sprintf("%s/%s", strings.TrimSuffix(c.Endpoint, "/"), c.Bucket)
+		default:
+			return ""
+		}
+	}
+	return strings.TrimSuffix(base, "/") + "/" + name
+}
+
+// SitePath is the output file name of a page in the league's season site,
+// e.g. "index.html" or "teams/<team ID>.html".
+func SitePath(l League, page string) string {
+	return fmt.Sprintf("%s/%s/%s", l.ID(), l.Season(), page)
+}
+
+// ChartPath is the output file name of a chart page for the league's week,
+// e.g. "index.html" or "<matchup ID>.html".
+func ChartPath(l League, week int, page string) string {
+	return SitePath(l, fmt.Sprintf("%d/%s", week, page))
+}
@@ -0,0 +1,139 @@
+package config
+
+import (
+	"math"
+	"sort"
+)
+
+// recentFormWeeks is how many of the latest weeks the recent form formula
+// looks at.
+const recentFormWeeks = 3
+
+// WeeklyScores is each team's final score by week and team ID.
+type WeeklyScores map[int]map[int64]float64
+
+// RankingFormula scores each team from the season's weekly scores through the
+// given week.  Higher scores rank higher.
+type RankingFormula func(weekly WeeklyScores, through int) map[int64]float64
+
+// RankingFormulas are the power ranking formulas leagues can weight in
+// power_rankings, by name.
+var RankingFormulas = map[string]RankingFormula{
+	// all_play is the win percentage each team would have if it played
+	// every other team every week.
+	"all_play": func(weekly WeeklyScores, through int) map[int64]float64 {
+		return allPlay(weekly, 1, through)
+	},
+	// points_for is each team's average score.
+	"points_for": pointsFor,
+	// recent_form is the all-play win percentage over the last few weeks.
+	"recent_form": func(weekly WeeklyScores, through int) map[int64]float64 {
+		return allPlay(weekly, through-recentFormWeeks+1, through)
+	},
+}
+
+// DefaultRankingWeights ranks by all-play record alone.
+var DefaultRankingWeights = map[string]float64{"all_play": 1}
+
+// RankingFormulaNames returns the names of RankingFormulas, sorted.
+func RankingFormulaNames() []string {
+	names := make([]string, 0, len(RankingFormulas))
+	for name := range RankingFormulas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// PowerRanking is a team's place in a week's power rankings.
+type PowerRanking struct {
+	TeamID int64   `firestore:"team_id"`
+	Rank   int     `firestore:"rank"`
+	Score  float64 `firestore:"score"`
+}
+
+func allPlay(weekly WeeklyScores, from int, through int) map[int64]float64 {
+	wins := make(map[int64]float64)
+	games := make(map[int64]int)
+	for week := from; week <= through; week++ {
+		scores := weekly[week]
+		for team, score := range scores {
+			if _, ok := wins[team]; !ok {
+				wins[team] = 0
+			}
+			for opp, oppScore := range scores {
+				if opp == team {
+					continue
+				}
+				games[team]++
+				if score > oppScore {
+					wins[team]++
+				} else if score == oppScore {
+					wins[team] += 0.5
+				}
+			}
+		}
+	}
+	for team := range wins {
+		if games[team] > 0 {
+			wins[team] /= float64(games[team])
+		}
+	}
+	return wins
+}
+
+func pointsFor(weekly WeeklyScores, through int) map[int64]float64 {
+	points := make(map[int64]float64)
+	weeks := make(map[int64]int)
+	for week := 1; week <= through; week++ {
+		for team, score := range weekly[week] {
+			points[team] += score
+			weeks[team]++
+		}
+	}
+	for team := range points {
+		points[team] /= float64(weeks[team])
+	}
+	return points
+}
+
+// PowerRankings ranks teams by a weighted sum of formulas, each scaled so the
+// worst team scores 0 and the best 1.  Ties are broken by team ID so the
+// rankings are stable.
+func PowerRankings(weights map[string]float64, weekly WeeklyScores, through int) []PowerRanking {
+	total := make(map[int64]float64)
+	for name, weight := range weights {
+		formula, ok := RankingFormulas[name]
+		if !ok {
+			continue
+		}
+		scores := formula(weekly, through)
+		lo, hi := math.Inf(1), math.Inf(-1)
+		for _, s := range scores {
+			lo = math.Min(lo, s)
+			hi = math.Max(hi, s)
+		}
+		for team, s := range scores {
+			scaled := 0.0
+			if hi > lo {
+				scaled = (s - lo) / (hi - lo)
+			}
+			total[team] += weight * scaled
+		}
+	}
+
+	rankings := make([]PowerRanking, 0, len(total))
+	for team, score := range total {
+		rankings = append(rankings, PowerRanking{TeamID: team, Score: score})
+	}
+	sort.Slice(rankings, func(i, j int) bool {
+		if rankings[i].Score != rankings[j].Score {
+			return rankings[i].Score > rankings[j].Score
+		}
+		return rankings[i].TeamID < rankings[j].TeamID
+	})
+	for i := range rankings {
+		rankings[i].Rank = i + 1
+	}
+	return rankings
+}
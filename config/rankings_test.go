@@ -0,0 +1,141 @@
+package config
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPowerRankings(t *testing.T) {
+	weekly := WeeklyScores{
+		1: {1: 100, 2: 90, 3: 80, 4: 70},
+		2: {1: 60, 2: 110, 3: 80, 4: 70},
+		3: {1: 90, 2: 50, 3: 100, 4: 70},
+		4: {1: 80, 2: 80, 3: 120, 4: 60},
+	}
+	// all-play through week 4 is 6.5, 6.5, 9 and 2 wins in 12 games, and
+	// over the last 3 weeks 3.5, 4.5, 8 and 2 in 9
+	tests := []struct {
+		name    string
+		weights map[string]float64
+		weekly  WeeklyScores
+		through int
+		want    []PowerRanking
+	}{
+		{
+			name:    "all-play ties broken by team ID",
+			weights: DefaultRankingWeights,
+			weekly:  weekly,
+			through: 4,
+			want: []PowerRanking{
+				{TeamID: 3, Rank: 1, Score: 1},
+				{TeamID: 1, Rank: 2, Score: 4.5 / 7},
+				{TeamID: 2, Rank: 3, Score: 4.5 / 7},
+				{TeamID: 4, Rank: 4, Score: 0},
+			},
+		},
+		{
+			name:    "points for",
+			weights: map[string]float64{"points_for": 1},
+			weekly:  weekly,
+			through: 4,
+			want: []PowerRanking{
+				{TeamID: 3, Rank: 1, Score: 1},
+				{TeamID: 1, Rank: 2, Score: 15 / 27.5},
+				{TeamID: 2, Rank: 3, Score: 15 / 27.5},
+				{TeamID: 4, Rank: 4, Score: 0},
+			},
+		},
+		{
+			name:    "recent form",
+			weights: map[string]float64{"recent_form": 1},
+			weekly:  weekly,
+			through: 4,
+			want: []PowerRanking{
+				{TeamID: 3, Rank: 1, Score: 1},
+				{TeamID: 2, Rank: 2, Score: 2.5 / 6},
+				{TeamID: 1, Rank: 3, Score: 1.5 / 6},
+				{TeamID: 4, Rank: 4, Score: 0},
+			},
+		},
+		{
+			name:    "weighted formulas",
+			weights: map[string]float64{"all_play": 2, "recent_form": 1},
+			weekly:  weekly,
+			through: 4,
+			want: []PowerRanking{
+				{TeamID: 3, Rank: 1, Score: 3},
+				{TeamID: 2, Rank: 2, Score: 9.0/7 + 2.5/6},
+				{TeamID: 1, Rank: 3, Score: 9.0/7 + 1.5/6},
+				{TeamID: 4, Rank: 4, Score: 0},
+			},
+		},
+		{
+			name:    "later weeks are left out",
+			weights: DefaultRankingWeights,
+			weekly:  weekly,
+			through: 1,
+			want: []PowerRanking{
+				{TeamID: 1, Rank: 1, Score: 1},
+				{TeamID: 2, Rank: 2, Score: 2.0 / 3},
+				{TeamID: 3, Rank: 3, Score: 1.0 / 3},
+				{TeamID: 4, Rank: 4, Score: 0},
+			},
+		},
+		{
+			name:    "unknown formulas are ignored",
+			weights: map[string]float64{"vibes": 5, "points_for": 1},
+			weekly:  weekly,
+			through: 1,
+			want: []PowerRanking{
+				{TeamID: 1, Rank: 1, Score: 1},
+				{TeamID: 2, Rank: 2, Score: 2.0 / 3},
+				{TeamID: 3, Rank: 3, Score: 1.0 / 3},
+				{TeamID: 4, Rank: 4, Score: 0},
+			},
+		},
+		{
+			name:    "every team even",
+			weights: map[string]float64{"all_play": 1, "points_for": 1},
+			weekly:  WeeklyScores{1: {2: 100, 1: 100}},
+			through: 1,
+			want: []PowerRanking{
+				{TeamID: 1, Rank: 1, Score: 0},
+				{TeamID: 2, Rank: 2, Score: 0},
+			},
+		},
+		{
+			name:    "no weeks",
+			weights: DefaultRankingWeights,
+			weekly:  WeeklyScores{},
+			through: 0,
+			want:    []PowerRanking{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := PowerRankings(tt.weights, tt.weekly, tt.through)
+			if len(got) != len(tt.want) {
+				t.Fatalf("PowerRankings() = %+v, want %+v", got, tt.want)
+			}
+			for i := range tt.want {
+				if got[i].TeamID != tt.want[i].TeamID || got[i].Rank != tt.want[i].Rank || math.Abs(got[i].Score-tt.want[i].Score) > 1e-9 {
+					t.Errorf("PowerRankings()[%d] = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRankingFormulaNames(t *testing.T) {
+	want := []string{"all_play", "points_for", "recent_form"}
+	got := RankingFormulaNames()
+	if len(got) != len(want) {
+		t.Fatalf("RankingFormulaNames() = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("RankingFormulaNames() = %q, want %q", got, want)
+			break
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseScheduleErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"0s",
+		"-5m",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+		"1-a * * * *",
+	}
+	for _, s := range tests {
+		if _, err := ParseSchedule(s, time.UTC); err == nil {
+			t.Errorf("ParseSchedule(%q) succeeded, want an error", s)
+		}
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a Saturday
+	from := time.Date(2022, 10, 1, 12, 34, 56, 0, time.UTC)
+	tests := []struct {
+		schedule string
+		loc      *time.Location
+		from     time.Time
+		want     time.Time
+	}{
+		{"5m", time.UTC, from, from.Add(5 * time.Minute)},
+		{"* * * * *", time.UTC, from, time.Date(2022, 10, 1, 12, 35, 0, 0, time.UTC)},
+		{"*/10 * * * *", time.UTC, from, time.Date(2022, 10, 1, 12, 40, 0, 0, time.UTC)},
+		{"0 * * * *", time.UTC, from, time.Date(2022, 10, 1, 13, 0, 0, 0, time.UTC)},
+		{"30 9 * * *", time.UTC, from, time.Date(2022, 10, 2, 9, 30, 0, 0, time.UTC)},
+		{"0 9 * * 2", time.UTC, from, time.Date(2022, 10, 4, 9, 0, 0, 0, time.UTC)},
+		// Sunday can be 0 or 7
+		{"0 9 * * 7", time.UTC, from, time.Date(2022, 10, 2, 9, 0, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.UTC, from, time.Date(2022, 11, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * 1 *", time.UTC, from, time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 12-14/2 * * *", time.UTC, from, time.Date(2022, 10, 1, 14, 0, 0, 0, time.UTC)},
+		{"0 0 * * 1,3", time.UTC, from, time.Date(2022, 10, 3, 0, 0, 0, 0, time.UTC)},
+		// with both days restricted, either one matching is enough
+		{"0 0 15 * 1", time.UTC, from, time.Date(2022, 10, 3, 0, 0, 0, 0, time.UTC)},
+		// evaluated in the schedule's timezone, not t's
+		{"0 9 * * 2", newYork, from, time.Date(2022, 10, 4, 13, 0, 0, 0, time.UTC)},
+		{"0 9 * * *", newYork, time.Date(2022, 10, 2, 12, 0, 0, 0, time.UTC), time.Date(2022, 10, 2, 13, 0, 0, 0, time.UTC)},
+		// and follow its daylight saving time
+		{"0 9 * * *", newYork, time.Date(2022, 11, 6, 0, 0, 0, 0, time.UTC), time.Date(2022, 11, 6, 14, 0, 0, 0, time.UTC)},
+		// February 30th never comes
+		{"0 0 30 2 *", time.UTC, from, time.Time{}},
+	}
+	for _, tt := range tests {
+		s, err := ParseSchedule(tt.schedule, tt.loc)
+		if err != nil {
+			t.Errorf("ParseSchedule(%q) error = %v", tt.schedule, err)
+			continue
+		}
+		if got := s.Next(tt.from); !got.Equal(tt.want) {
+			t.Errorf("ParseSchedule(%q, %s).Next(%s) = %s, want %s", tt.schedule, tt.loc, tt.from, got, tt.want)
+		}
+	}
+}
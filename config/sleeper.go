@@ -0,0 +1,589 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/craigatron/sleeper-go"
+)
+
+const (
+	sleeperAPIURL         = "https://api.sleeper.app/v1"
+	sleeperProjectionsURL = "https://api.sleeper.app"
+)
+
+// sleeperPlayersRetry is how long to wait before fetching the Sleeper player
+// database again after it fails.
+const sleeperPlayersRetry = time.Minute
+
+// SleeperLeague is a League backed by the Sleeper API.
+type SleeperLeague struct {
+	// league holds a *sleeper.League that's replaced whole on Refresh, so
+	// it's safe to read while refreshing.
+	league atomic.Value
+	config LeagueConfigJSON
+
+	httpClient *http.Client
+
+	playersMu sync.Mutex
+	// players is the Sleeper player database keyed by player ID, or nil until
+	// it's been fetched.
+	players       map[string]Player
+	playersFailed time.Time
+}
+
+// NewSleeperLeague creates a League for the given Sleeper league config.
+func NewSleeperLeague(l LeagueConfigJSON, token string) (*SleeperLeague, error) {
+	league, err := sleeper.NewLeague(l.ID, token)
+	if err != nil {
+		return nil, err
+	}
+	sl := &SleeperLeague{
+		config:     l,
+		httpClient: &http.Client{Timeout: time.Minute},
+	}
+	sl.league.Store(&league)
+	return sl, nil
+}
+
+// current is the league as of the last refresh.
+func (l *SleeperLeague) current() *sleeper.League {
+	return l.league.Load().(*sleeper.League)
+}
+
+// ID is the Sleeper league ID.
+func (l *SleeperLeague) ID() string {
+	return l.current().ID
+}
+
+// Type is always LeagueTypeSleeper.
+func (l *SleeperLeague) Type() LeagueType {
+	return LeagueTypeSleeper
+}
+
+// Season is the league's season.
+func (l *SleeperLeague) Season() string {
+	return l.current().Season
+}
+
+// CurrentWeek fetches the current week from the Sleeper NFL status.
+func (l *SleeperLeague) CurrentWeek() (int, error) {
+	status, err := l.current().Client.GetNflStatus()
+	if err != nil {
+		return 0, err
+	}
+	return status.Week, nil
+}
+
+// Teams returns the league's rosters keyed by roster ID.  Sleeper teams are
+// named by their owner.
+func (l *SleeperLeague) Teams() map[int64]Team {
+	league := l.current()
+	teams := make(map[int64]Team)
+	for _, r := range league.Rosters {
+		owner := league.Users[r.OwnerID]
+		var teamName string
+		if owner.Metadata.TeamName != "" {
+			teamName = owner.Metadata.TeamName
+		} else {
+			teamName = owner.DisplayName
+		}
+		teams[int64(r.RosterID)] = Team{
+			ID:       int64(r.RosterID),
+			Name:     teamName,
+			OwnerIDs: []string{r.OwnerID},
+		}
+	}
+	return teams
+}
+
+// Members returns the league's users keyed by user ID.
+func (l *SleeperLeague) Members() map[string]Member {
+	members := make(map[string]Member)
+	for _, u := range l.current().Users {
+		members[u.UserID] = Member{
+			ID:          u.UserID,
+			DisplayName: u.DisplayName,
+		}
+	}
+	return members
+}
+
+// Matchups fetches the matchups for the given week.
+func (l *SleeperLeague) Matchups(week int) ([]Matchup, error) {
+	sm, err := l.current().Client.GetLeagueMatchups(l.current().ID, week)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int]*Matchup)
+	matchups := make([]*Matchup, 0)
+	for _, m := range sm {
+		// matchup ID is null for teams on bye in the playoffs
+		if m.MatchupID == 0 {
+			continue
+		}
+		matchup, ok := byID[m.MatchupID]
+		if !ok {
+			matchup = &Matchup{
+				ID:         int64(m.MatchupID),
+				Week:       week,
+				HomeTeamID: int64(m.RosterID),
+				HomeScore:  float64(m.Points),
+			}
+			byID[m.MatchupID] = matchup
+			matchups = append(matchups, matchup)
+		} else {
+			matchup.AwayTeamID = int64(m.RosterID)
+			matchup.AwayScore = float64(m.Points)
+		}
+	}
+	res := make([]Matchup, 0, len(matchups))
+	for _, m := range matchups {
+		res = append(res, *m)
+	}
+	return res, nil
+}
+
+// Projections fetches each roster's projected score for the current week.
+func (l *SleeperLeague) Projections() ([]Projection, error) {
+	sp, err := l.current().GetProjections()
+	if err != nil {
+		return nil, err
+	}
+	projections := make([]Projection, 0, len(sp))
+	for _, p := range sp {
+		projections = append(projections, Projection{
+			MatchupID:  int64(p.Matchup.MatchupID),
+			TeamID:     int64(p.Matchup.RosterID),
+			Projection: p.Projection,
+		})
+	}
+	return projections, nil
+}
+
+type sleeperLineupsJSON []struct {
+	RosterID      int                `json:"roster_id"`
+	Starters      []string           `json:"starters"`
+	Players       []string           `json:"players"`
+	PlayersPoints map[string]float64 `json:"players_points"`
+}
+
+type sleeperPlayerProjectionsJSON []struct {
+	PlayerID string             `json:"player_id"`
+	Stats    map[string]float64 `json:"stats"`
+}
+
+// sleeperPointsKey is the stat with points in the PPR setting closest to a
+// league's scoring.
+func sleeperPointsKey(scoring map[string]float64) string {
+	switch rec := scoring["rec"]; {
+	case rec >= 1:
+		return "pts_ppr"
+	case rec > 0:
+		return "pts_half_ppr"
+	}
+	return "pts_std"
+}
+
+type sleeperPlayerStatsJSON struct {
+	Stats map[string]float64 `json:"stats"`
+}
+
+// SeasonPoints fetches a player's regular season points in the PPR setting
+// closest to the league's scoring.
+func (l *SleeperLeague) SeasonPoints(playerID string) (float64, error) {
+	league := l.current()
+	stats := sleeperPlayerStatsJSON{}
+	statsURL := fmt.Sprintf("%s/stats/nfl/player/%s?season_type=regular&season=%s", sleeperProjectionsURL, url.PathEscape(playerID), league.Season)
+	if err := l.sendRequest(statsURL, &stats); err != nil {
+		return 0, err
+	}
+	return stats.Stats[sleeperPointsKey(league.LeagueInfo.ScoringSettings)], nil
+}
+
+// Lineups fetches each roster's lineup for the given week.  Player
+// projections use the PPR setting closest to the league's scoring.
+func (l *SleeperLeague) Lineups(week int) (map[int64]Lineup, error) {
+	league := l.current()
+	matchups := sleeperLineupsJSON{}
+	if err := l.sendRequest(fmt.Sprintf("/league/%s/matchups/%d", league.ID, week), &matchups); err != nil {
+		return nil, err
+	}
+
+	projected := make(map[string]float64)
+	playerProjections := sleeperPlayerProjectionsJSON{}
+	projectionsURL := fmt.Sprintf("%s/projections/nfl/%s/%d?season_type=regular", sleeperProjectionsURL, league.Season, week)
+	if err := l.sendRequest(projectionsURL, &playerProjections); err != nil {
+		return nil, err
+	}
+	pointsKey := sleeperPointsKey(league.LeagueInfo.ScoringSettings)
+	for _, p := range playerProjections {
+		projected[p.PlayerID] = p.Stats[pointsKey]
+	}
+
+	// starters line up with the league's non-bench roster positions
+	slots := make([]string, 0)
+	for _, pos := range league.LeagueInfo.RosterPositions {
+		if pos != "BN" {
+			slots = append(slots, pos)
+		}
+	}
+
+	lineups := make(map[int64]Lineup)
+	for _, m := range matchups {
+		lineup := Lineup{TeamID: int64(m.RosterID), Players: make([]LineupPlayer, 0, len(m.Players))}
+		starters := make(map[string]bool)
+		for i, id := range m.Starters {
+			// empty lineup slots are "0"
+			if id == "0" {
+				continue
+			}
+			starters[id] = true
+			slot := ""
+			if i < len(slots) {
+				slot = slots[i]
+			}
+			lineup.Players = append(lineup.Players, LineupPlayer{
+				PlayerID:  id,
+				Slot:      slot,
+				Starter:   true,
+				Points:    m.PlayersPoints[id],
+				Projected: projected[id],
+			})
+		}
+		for _, id := range m.Players {
+			if starters[id] {
+				continue
+			}
+			lineup.Players = append(lineup.Players, LineupPlayer{
+				PlayerID:  id,
+				Slot:      "BN",
+				Points:    m.PlayersPoints[id],
+				Projected: projected[id],
+			})
+		}
+		lineups[int64(m.RosterID)] = lineup
+	}
+	return lineups, nil
+}
+
+// Rosters returns each roster's players keyed by roster ID.
+func (l *SleeperLeague) Rosters() (map[int64]Roster, error) {
+	rosters := make(map[int64]Roster)
+	for _, r := range l.current().Rosters {
+		rosters[int64(r.RosterID)] = Roster{
+			TeamID:    int64(r.RosterID),
+			PlayerIDs: r.Players,
+			Starters:  r.Starters,
+		}
+	}
+	return rosters, nil
+}
+
+type sleeperStandingsJSON []struct {
+	RosterID int `json:"roster_id"`
+	Settings struct {
+		Wins               int `json:"wins"`
+		Losses             int `json:"losses"`
+		Ties               int `json:"ties"`
+		Fpts               int `json:"fpts"`
+		FptsDecimal        int `json:"fpts_decimal"`
+		FptsAgainst        int `json:"fpts_against"`
+		FptsAgainstDecimal int `json:"fpts_against_decimal"`
+		Division           int `json:"division"`
+	} `json:"settings"`
+	Metadata struct {
+		// e.g. "3W"
+		Streak string `json:"streak"`
+	} `json:"metadata"`
+}
+
+// Standings fetches each roster's record from Sleeper.
+func (l *SleeperLeague) Standings() ([]Standing, error) {
+	rosters := sleeperStandingsJSON{}
+	if err := l.sendRequest(fmt.Sprintf("/league/%s/rosters", l.current().ID), &rosters); err != nil {
+		return nil, err
+	}
+	info := struct {
+		Metadata map[string]string `json:"metadata"`
+	}{}
+	if err := l.sendRequest(fmt.Sprintf("/league/%s", l.current().ID), &info); err != nil {
+		return nil, err
+	}
+
+	standings := make([]Standing, 0, len(rosters))
+	for _, r := range rosters {
+		s := Standing{
+			TeamID:        int64(r.RosterID),
+			Wins:          r.Settings.Wins,
+			Losses:        r.Settings.Losses,
+			Ties:          r.Settings.Ties,
+			PointsFor:     float64(r.Settings.Fpts) + float64(r.Settings.FptsDecimal)/100,
+			PointsAgainst: float64(r.Settings.FptsAgainst) + float64(r.Settings.FptsAgainstDecimal)/100,
+		}
+		if r.Settings.Division > 0 {
+			s.Division = info.Metadata[fmt.Sprintf("division_%d", r.Settings.Division)]
+			if s.Division == "" {
+				s.Division = fmt.Sprintf("Division %d", r.Settings.Division)
+			}
+		}
+		if n := len(r.Metadata.Streak); n > 1 {
+			s.Streak = r.Metadata.Streak[n-1:] + r.Metadata.Streak[:n-1]
+		}
+		standings = append(standings, s)
+	}
+	SortStandings(standings)
+	return standings, nil
+}
+
+type sleeperTransactionJSON struct {
+	TransactionID string         `json:"transaction_id"`
+	Type          string         `json:"type"`
+	Status        string         `json:"status"`
+	StatusUpdated int64          `json:"status_updated"`
+	Adds          map[string]int `json:"adds"`
+	Drops         map[string]int `json:"drops"`
+	DraftPicks    []struct {
+		Season          string `json:"season"`
+		Round           int    `json:"round"`
+		RosterID        int    `json:"roster_id"`
+		PreviousOwnerID int    `json:"previous_owner_id"`
+		OwnerID         int    `json:"owner_id"`
+	} `json:"draft_picks"`
+	WaiverBudget []struct {
+		Sender   int `json:"sender"`
+		Receiver int `json:"receiver"`
+		Amount   int `json:"amount"`
+	} `json:"waiver_budget"`
+	Settings struct {
+		WaiverBid int `json:"waiver_bid"`
+	} `json:"settings"`
+}
+
+type sleeperTransactionsJSON []sleeperTransactionJSON
+
+// RecentActivity returns completed transactions, newest first.  Sleeper
+// serves transactions by week, so weeks are fetched from the current week
+// back until there are enough.
+func (l *SleeperLeague) RecentActivity(limit int, offset int) ([]Activity, error) {
+	week, err := l.CurrentWeek()
+	if err != nil {
+		return nil, err
+	}
+
+	teams := l.Teams()
+	activity := make([]Activity, 0)
+	for ; week >= 0 && len(activity) < offset+limit; week-- {
+		transactions := sleeperTransactionsJSON{}
+		if err := l.sendRequest(fmt.Sprintf("/league/%s/transactions/%d", l.current().ID, week), &transactions); err != nil {
+			return nil, err
+		}
+		weekActivity := make([]Activity, 0, len(transactions))
+		for _, t := range transactions {
+			if t.Status != "complete" {
+				continue
+			}
+			weekActivity = append(weekActivity, sleeperActivity(t, teams))
+		}
+		sort.Slice(weekActivity, func(i, j int) bool {
+			return weekActivity[i].Timestamp > weekActivity[j].Timestamp
+		})
+		activity = append(activity, weekActivity...)
+	}
+
+	if offset >= len(activity) {
+		return []Activity{}, nil
+	}
+	end := offset + limit
+	if end > len(activity) {
+		end = len(activity)
+	}
+	return activity[offset:end], nil
+}
+
+// sleeperActivity converts a transaction, naming traded picks by the team
+// they originally belonged to.
+func sleeperActivity(t sleeperTransactionJSON, teams map[int64]Team) Activity {
+	a := Activity{
+		ID:        t.TransactionID,
+		Timestamp: t.StatusUpdated,
+		Actions:   sleeperActions(t.Type, t.Adds, t.Drops, t.Settings.WaiverBid),
+	}
+	for _, p := range t.DraftPicks {
+		a.Actions = append(a.Actions, ActivityAction{
+			Action: ActionTraded,
+			TeamID: int64(p.OwnerID),
+			Pick:   fmt.Sprintf("%s round %d (%s)", p.Season, p.Round, teams[int64(p.RosterID)].Name),
+		})
+	}
+	for _, b := range t.WaiverBudget {
+		a.Actions = append(a.Actions, ActivityAction{
+			Action: ActionTraded,
+			TeamID: int64(b.Receiver),
+			FAAB:   b.Amount,
+		})
+	}
+	return a
+}
+
+// sleeperActions converts a transaction's adds and drops, which map player IDs
+// to roster IDs.
+func sleeperActions(transactionType string, adds map[string]int, drops map[string]int, waiverBid int) []ActivityAction {
+	addAction := ActionAdded
+	bid := 0
+	switch transactionType {
+	case "waiver":
+		addAction = ActionWaiverAdded
+		bid = waiverBid
+	case "trade":
+		addAction = ActionTraded
+	}
+
+	actions := make([]ActivityAction, 0, len(adds)+len(drops))
+	for playerID, rosterID := range adds {
+		actions = append(actions, ActivityAction{
+			Action:   addAction,
+			PlayerID: playerID,
+			TeamID:   int64(rosterID),
+			FAAB:     bid,
+		})
+	}
+	// players traded away show up as drops too, so they're covered by the adds
+	if transactionType != "trade" {
+		for playerID, rosterID := range drops {
+			actions = append(actions, ActivityAction{
+				Action:   ActionDropped,
+				PlayerID: playerID,
+				TeamID:   int64(rosterID),
+			})
+		}
+	}
+	sort.Slice(actions, func(i, j int) bool {
+		if actions[i].TeamID != actions[j].TeamID {
+			return actions[i].TeamID < actions[j].TeamID
+		}
+		return actions[i].PlayerID < actions[j].PlayerID
+	})
+	return actions
+}
+
+type sleeperPlayersJSON map[string]struct {
+	FullName  string `json:"full_name"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Position  string `json:"position"`
+	// Team and InjuryStatus are null for free agents and healthy players.
+	Team         string `json:"team"`
+	InjuryStatus string `json:"injury_status"`
+}
+
+// loadPlayers returns the Sleeper player database, fetching it on first use.
+// If the fetch fails it's tried again on a later call, once
+// sleeperPlayersRetry has passed.
+func (l *SleeperLeague) loadPlayers() (map[string]Player, error) {
+	l.playersMu.Lock()
+	defer l.playersMu.Unlock()
+	if l.players != nil {
+		return l.players, nil
+	}
+	if time.Since(l.playersFailed) < sleeperPlayersRetry {
+		return nil, fmt.Errorf("fetching Sleeper players failed less than %s ago", sleeperPlayersRetry)
+	}
+
+	sp := sleeperPlayersJSON{}
+	if err := l.sendRequest("/players/nfl", &sp); err != nil {
+		l.playersFailed = time.Now()
+		return nil, err
+	}
+	players := make(map[string]Player, len(sp))
+	for id, p := range sp {
+		player := Player{
+			ID:           id,
+			FullName:     p.FullName,
+			Position:     p.Position,
+			NFLTeam:      p.Team,
+			InjuryStatus: injuryStatus(p.InjuryStatus),
+		}
+		// team defenses don't have a full name
+		if player.FullName == "" {
+			player.FullName = p.FirstName + " " + p.LastName
+		}
+		players[id] = player
+	}
+	l.players = players
+	return players, nil
+}
+
+// Player looks up an NFL player in the Sleeper player database, which is
+// fetched on first use.
+func (l *SleeperLeague) Player(id string) (Player, bool) {
+	players, err := l.loadPlayers()
+	if err != nil {
+		return Player{}, false
+	}
+	p, ok := players[id]
+	return p, ok
+}
+
+// Players returns every player in the Sleeper player database, or none if it
+// can't be fetched.
+func (l *SleeperLeague) Players() []Player {
+	players, err := l.loadPlayers()
+	if err != nil {
+		return []Player{}
+	}
+	res := make([]Player, 0, len(players))
+	for _, p := range players {
+		res = append(res, p)
+	}
+	return res
+}
+
+// Refresh reloads league info, rosters and users from Sleeper.
+func (l *SleeperLeague) Refresh() error {
+	current := l.current()
+	league, err := sleeper.NewLeague(current.ID, current.Token)
+	if err != nil {
+		return err
+	}
+	l.league.Store(&league)
+	return nil
+}
+
+// Config is the config this league was created from.
+func (l *SleeperLeague) Config() LeagueConfigJSON {
+	return l.config
+}
+
+// sendRequest fetches a Sleeper API path that the Sleeper client doesn't
+// expose.  Paths that are already full URLs are fetched as is.
+func (l *SleeperLeague) sendRequest(path string, v interface{}) error {
+	return sleeperGet(l.httpClient, path, v)
+}
+
+// sleeperGet decodes the JSON at path, which is relative to the Sleeper API
+// unless it's a full URL.
+func sleeperGet(client *http.Client, path string, v interface{}) error {
+	u := path
+	if !strings.HasPrefix(path, "https://") {
+		u = sleeperAPIURL + path
+	}
+	res, err := client.Get(u)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("unknown error fetching Sleeper path %s, status code: %d", path, res.StatusCode)
+	}
+
+	return json.NewDecoder(res.Body).Decode(v)
+}
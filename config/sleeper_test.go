@@ -0,0 +1,101 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestSleeperActivity(t *testing.T) {
+	teams := map[int64]Team{
+		1: {ID: 1, Name: "Team One"},
+		2: {ID: 2, Name: "Team Two"},
+	}
+	tests := []struct {
+		name string
+		raw  string
+		want Activity
+	}{
+		{
+			name: "free agent add and drop",
+			raw: `{
+				"transaction_id": "100", "type": "free_agent", "status": "complete", "status_updated": 1666000000000,
+				"adds": {"4034": 1}, "drops": {"2133": 1}
+			}`,
+			want: Activity{
+				ID:        "100",
+				Timestamp: 1666000000000,
+				Actions: []ActivityAction{
+					{Action: ActionDropped, PlayerID: "2133", TeamID: 1},
+					{Action: ActionAdded, PlayerID: "4034", TeamID: 1},
+				},
+			},
+		},
+		{
+			name: "waiver claim with a bid",
+			raw: `{
+				"transaction_id": "101", "type": "waiver", "status": "complete", "status_updated": 1666000000001,
+				"adds": {"4034": 2}, "drops": null, "settings": {"waiver_bid": 12}
+			}`,
+			want: Activity{
+				ID:        "101",
+				Timestamp: 1666000000001,
+				Actions: []ActivityAction{
+					{Action: ActionWaiverAdded, PlayerID: "4034", TeamID: 2, FAAB: 12},
+				},
+			},
+		},
+		{
+			name: "trade with picks and budget",
+			raw: `{
+				"transaction_id": "102", "type": "trade", "status": "complete", "status_updated": 1666000000002,
+				"adds": {"4034": 2, "2133": 1}, "drops": {"4034": 1, "2133": 2},
+				"draft_picks": [{"season": "2023", "round": 1, "roster_id": 1, "previous_owner_id": 1, "owner_id": 2}],
+				"waiver_budget": [{"sender": 2, "receiver": 1, "amount": 5}]
+			}`,
+			want: Activity{
+				ID:        "102",
+				Timestamp: 1666000000002,
+				Actions: []ActivityAction{
+					{Action: ActionTraded, PlayerID: "2133", TeamID: 1},
+					{Action: ActionTraded, PlayerID: "4034", TeamID: 2},
+					{Action: ActionTraded, TeamID: 2, Pick: "2023 round 1 (Team One)"},
+					{Action: ActionTraded, TeamID: 1, FAAB: 5},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var transaction sleeperTransactionJSON
+			if err := json.Unmarshal([]byte(tt.raw), &transaction); err != nil {
+				t.Fatal(err)
+			}
+			got := sleeperActivity(transaction, teams)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sleeperActivity() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSleeperPointsKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		scoring map[string]float64
+		want    string
+	}{
+		{"ppr", map[string]float64{"rec": 1}, "pts_ppr"},
+		{"te premium", map[string]float64{"rec": 1.5}, "pts_ppr"},
+		{"half ppr", map[string]float64{"rec": 0.5}, "pts_half_ppr"},
+		{"standard", map[string]float64{"rec": 0}, "pts_std"},
+		{"no scoring settings", nil, "pts_std"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sleeperPointsKey(tt.scoring); got != tt.want {
+				t.Errorf("sleeperPointsKey(%v) = %q, want %q", tt.scoring, got, tt.want)
+			}
+		})
+	}
+}
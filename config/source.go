@@ -0,0 +1,146 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"cloud.google.com/go/storage"
+)
+
+// Source is somewhere the JSON config can be read from.
+type Source interface {
+	// Read returns the raw JSON config.
+	Read(ctx context.Context) ([]byte, error)
+	// Version identifies the current revision of the config, changing
+	// whenever the config does.
+	Version(ctx context.Context) (string, error)
+	// String describes the source for logging.
+	String() string
+}
+
+// FileSource reads the config from a local file.
+type FileSource struct {
+	Path string
+}
+
+// Read reads the config file.
+func (s FileSource) Read(ctx context.Context) ([]byte, error) {
+	return ioutil.ReadFile(s.Path)
+}
+
+// Version is the file's modification time.
+func (s FileSource) Version(ctx context.Context) (string, error) {
+	info, err := os.Stat(s.Path)
+	if err != nil {
+		return "", err
+	}
+	return info.ModTime().String(), nil
+}
+
+func (s FileSource) String() string {
+	return fmt.Sprintf("file %s", s.Path)
+}
+
+// GCSSource reads the config from a Cloud Storage object.
+type GCSSource struct {
+	Bucket string
+	Object string
+}
+
+// Read fetches the config object from GCS.
+func (s GCSSource) Read(ctx context.Context) ([]byte, error) {
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	r, err := client.Bucket(s.Bucket).Object(s.Object).NewReader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return ioutil.ReadAll(r)
+}
+
+// Version is the config object's generation.
+func (s GCSSource) Version(ctx context.Context) (string, error) {
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer client.Close()
+
+	attrs, err := client.Bucket(s.Bucket).Object(s.Object).Attrs(ctx)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d", attrs.Generation), nil
+}
+
+func (s GCSSource) String() string {
+	return fmt.Sprintf("gs://%s/%s", s.Bucket, s.Object)
+}
+
+// DefaultSource picks the config source, in order of precedence:
+//   - the given path, e.g. from a command line flag
+//   - the local file named by CONFIG_FILE
+//   - the GCS object named by CONFIG_BUCKET and CONFIG_OBJECT
+func DefaultSource(path string) (Source, error) {
+	if path != "" {
+		return FileSource{Path: path}, nil
+	}
+	if p := os.Getenv("CONFIG_FILE"); p != "" {
+		return FileSource{Path: p}, nil
+	}
+
+	configBucket := os.Getenv("CONFIG_BUCKET")
+	configObject := os.Getenv("CONFIG_OBJECT")
+	if configBucket == "" || configObject == "" {
+		return nil, errors.New("no CONFIG_FILE or CONFIG_BUCKET and CONFIG_OBJECT provided")
+	}
+	return GCSSource{Bucket: configBucket, Object: configObject}, nil
+}
+
+// ApplyEnvOverrides replaces secrets in the config with any values set in the
+// environment, so they don't need to live in the config file.  lookup is
+// usually os.LookupEnv.
+//
+//	DISCORD_APP_ID         overrides appId
+//	DISCORD_TOKEN          overrides token
+//	ESPN_SWID              overrides espn_config.swid
+//	ESPN_S2                overrides espn_config.s2
+//	SLEEPER_TOKEN          overrides sleeper_config.token
+//	AWS_ACCESS_KEY_ID      overrides output.access_key_id
+//	AWS_SECRET_ACCESS_KEY  overrides output.secret_access_key
+//
+// PROJECTION_BUCKET is also still honored as shorthand for a gcs output when
+// no output is configured.
+func ApplyEnvOverrides(c *JSON, lookup func(string) (string, bool)) {
+	overrides := []struct {
+		env   string
+		field *string
+	}{
+		{"DISCORD_APP_ID", &c.AppID},
+		{"DISCORD_TOKEN", &c.Token},
+		{"ESPN_SWID", &c.ESPNConfig.SWID},
+		{"ESPN_S2", &c.ESPNConfig.ESPNS2},
+		{"SLEEPER_TOKEN", &c.SleeperConfig.Token},
+		{"AWS_ACCESS_KEY_ID", &c.OutputConfig.AccessKeyID},
+		{"AWS_SECRET_ACCESS_KEY", &c.OutputConfig.SecretAccessKey},
+	}
+	for _, o := range overrides {
+		if v, ok := lookup(o.env); ok {
+			*o.field = v
+		}
+	}
+
+	if bucket, ok := lookup("PROJECTION_BUCKET"); ok && bucket != "" && c.OutputConfig.Type == "" {
+		c.OutputConfig.Type = "gcs"
+		c.OutputConfig.Bucket = bucket
+	}
+}
@@ -0,0 +1,127 @@
+package config
+
+import "testing"
+
+func TestDefaultSource(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		env     map[string]string
+		want    Source
+		wantErr bool
+	}{
+		{
+			name: "path",
+			path: "config.json",
+			env:  map[string]string{"CONFIG_FILE": "other.json", "CONFIG_BUCKET": "bucket", "CONFIG_OBJECT": "object"},
+			want: FileSource{Path: "config.json"},
+		},
+		{
+			name: "config file",
+			env:  map[string]string{"CONFIG_FILE": "other.json", "CONFIG_BUCKET": "bucket", "CONFIG_OBJECT": "object"},
+			want: FileSource{Path: "other.json"},
+		},
+		{
+			name: "gcs",
+			env:  map[string]string{"CONFIG_BUCKET": "bucket", "CONFIG_OBJECT": "object"},
+			want: GCSSource{Bucket: "bucket", Object: "object"},
+		},
+		{
+			name:    "bucket without object",
+			env:     map[string]string{"CONFIG_BUCKET": "bucket"},
+			wantErr: true,
+		},
+		{
+			name:    "nothing",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, k := range []string{"CONFIG_FILE", "CONFIG_BUCKET", "CONFIG_OBJECT"} {
+				t.Setenv(k, tt.env[k])
+			}
+			got, err := DefaultSource(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DefaultSource(%q) error = %v, wantErr %t", tt.path, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("DefaultSource(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyEnvOverrides(t *testing.T) {
+	tests := []struct {
+		name   string
+		config JSON
+		env    map[string]string
+		check  func(c JSON) bool
+	}{
+		{
+			name:   "secrets",
+			config: JSON{AppID: "app", Token: "token"},
+			env: map[string]string{
+				"DISCORD_APP_ID":        "env-app",
+				"DISCORD_TOKEN":         "env-token",
+				"ESPN_SWID":             "env-swid",
+				"ESPN_S2":               "env-s2",
+				"SLEEPER_TOKEN":         "env-sleeper",
+				"AWS_ACCESS_KEY_ID":     "env-key",
+				"AWS_SECRET_ACCESS_KEY": "env-secret",
+			},
+			check: func(c JSON) bool {
+				return c.AppID == "env-app" &&
+					c.Token == "env-token" &&
+					c.ESPNConfig.SWID == "env-swid" &&
+					c.ESPNConfig.ESPNS2 == "env-s2" &&
+					c.SleeperConfig.Token == "env-sleeper" &&
+					c.OutputConfig.AccessKeyID == "env-key" &&
+					c.OutputConfig.SecretAccessKey == "env-secret"
+			},
+		},
+		{
+			name:   "unset keeps config",
+			config: JSON{AppID: "app", Token: "token"},
+			check: func(c JSON) bool {
+				return c.AppID == "app" && c.Token == "token"
+			},
+		},
+		{
+			name:   "set but empty clears config",
+			config: JSON{Token: "token"},
+			env:    map[string]string{"DISCORD_TOKEN": ""},
+			check: func(c JSON) bool {
+				return c.Token == ""
+			},
+		},
+		{
+			name: "projection bucket",
+			env:  map[string]string{"PROJECTION_BUCKET": "bucket"},
+			check: func(c JSON) bool {
+				return c.OutputConfig.Type == "gcs" && c.OutputConfig.Bucket == "bucket"
+			},
+		},
+		{
+			name:   "projection bucket doesn't replace output",
+			config: JSON{OutputConfig: OutputConfigJSON{Type: "local", Path: "out"}},
+			env:    map[string]string{"PROJECTION_BUCKET": "bucket"},
+			check: func(c JSON) bool {
+				return c.OutputConfig.Type == "local" && c.OutputConfig.Bucket == ""
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := tt.config
+			ApplyEnvOverrides(&c, func(k string) (string, bool) {
+				v, ok := tt.env[k]
+				return v, ok
+			})
+			if !tt.check(c) {
+				t.Errorf("ApplyEnvOverrides() = %+v", c)
+			}
+		})
+	}
+}
@@ -0,0 +1,268 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// earliestESPNYear is the first season the ESPN v3 API has data for.
+const earliestESPNYear = 2018
+
+// ValidationError lists every problem found in a config.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%d config problem(s):\n  %s", len(e.Problems), strings.Join(e.Problems, "\n  "))
+}
+
+// Validate checks the config for missing or malformed fields, returning a
+// *ValidationError listing all of them.  The Discord app ID and token aren't
+// required, since only the bot uses them; see ValidateBot.
+func (c JSON) Validate() error {
+	return c.validate(false)
+}
+
+// ValidateBot is Validate, but also requires the Discord app ID and token.
+func (c JSON) ValidateBot() error {
+	return c.validate(true)
+}
+
+func (c JSON) validate(bot bool) error {
+	problems := make([]string, 0)
+	addf := func(format string, a ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, a...))
+	}
+
+	for _, k := range c.unknownKeys {
+		addf("unknown key %s", k)
+	}
+
+	if bot && c.AppID == "" {
+		addf("appId is required")
+	}
+	if bot && c.Token == "" {
+		addf("token is required")
+	}
+
+	for i, g := range c.CommandGuildIDs {
+		if g == "" {
+			addf("command_guild_ids[%d] is empty", i)
+		}
+	}
+
+	for i, r := range c.ReaccConfig.Reaccs {
+		if r.Reacc == "" {
+			addf("reacc_config.reaccs[%d]: reacc is required", i)
+		}
+		if _, err := regexp.Compile(r.Pattern); err != nil {
+			addf("reacc_config.reaccs[%d]: invalid pattern %q: %s", i, r.Pattern, err)
+		}
+	}
+	for i, r := range c.ReaccConfig.IgnoreReaccs {
+		if r.UserID == "" || r.IgnoreReacc == "" {
+			addf("reacc_config.ignore_reaccs[%d]: user_id and ignore_reacc are required", i)
+		}
+	}
+
+	if len(c.LeagueConfig) == 0 {
+		addf("leagues: at least one league is required")
+	}
+
+	hasESPN := false
+	leagueIDs := make(map[string]int)
+	categoryIDs := make(map[string]int)
+	for i, l := range c.LeagueConfig {
+		prefix := fmt.Sprintf("leagues[%d]", i)
+		if l.Name != "" {
+			prefix = fmt.Sprintf("%s (%s)", prefix, l.Name)
+		}
+
+		switch l.LeagueType {
+		case "espn":
+			hasESPN = true
+		case "sleeper":
+		default:
+			addf("%s: type must be \"espn\" or \"sleeper\", got %q", prefix, l.LeagueType)
+		}
+
+		if l.ID == "" {
+			addf("%s: id is required", prefix)
+		} else {
+			key := l.LeagueType + "-" + l.ID
+			if j, ok := leagueIDs[key]; ok {
+				addf("%s: duplicate %s league id %s, also used by leagues[%d]", prefix, l.LeagueType, l.ID, j)
+			} else {
+				leagueIDs[key] = i
+			}
+		}
+
+		if len(l.DiscordCategoryIDs) == 0 {
+			addf("%s: discord_category_ids is required", prefix)
+		}
+		for user, owner := range l.Links {
+			if user == "" || owner == "" {
+				addf("%s: links must map a Discord user ID to an owner ID", prefix)
+			}
+		}
+		if _, err := l.Location(); err != nil {
+			addf("%s: timezone: %s", prefix, err)
+		}
+		for name, weight := range l.PowerRankings {
+			if _, ok := RankingFormulas[name]; !ok {
+				addf("%s: power_rankings: unknown formula %q, must be one of %s", prefix, name, strings.Join(RankingFormulaNames(), ", "))
+			} else if weight <= 0 {
+				addf("%s: power_rankings: %s weight must be positive, got %g", prefix, name, weight)
+			}
+		}
+		for _, d := range l.DiscordCategoryIDs {
+			if j, ok := categoryIDs[d]; ok {
+				addf("%s: discord category %s is already mapped to leagues[%d]", prefix, d, j)
+			} else {
+				categoryIDs[d] = i
+			}
+		}
+	}
+
+	if hasESPN {
+		maxYear := time.Now().Year() + 1
+		if c.ESPNConfig.Year < earliestESPNYear || c.ESPNConfig.Year > maxYear {
+			addf("espn_config.year must be between %d and %d, got %d", earliestESPNYear, maxYear, c.ESPNConfig.Year)
+		}
+		if (c.ESPNConfig.SWID == "") != (c.ESPNConfig.ESPNS2 == "") {
+			addf("espn_config: swid and s2 must be set together")
+		}
+	}
+
+	switch c.StorageConfig.Type {
+	case "", "firestore", "memory":
+	case "sqlite":
+		if c.StorageConfig.Path == "" {
+			addf("storage: path is required for sqlite")
+		}
+	default:
+		addf("storage: type must be \"firestore\", \"sqlite\" or \"memory\", got %q", c.StorageConfig.Type)
+	}
+
+	switch c.OutputConfig.Type {
+	case "":
+	case "gcs":
+		if c.OutputConfig.Bucket == "" {
+			addf("output: bucket is required for gcs")
+		}
+	case "local":
+		if c.OutputConfig.Path == "" {
+			addf("output: path is required for local")
+		}
+	case "s3":
+		if c.OutputConfig.Bucket == "" || c.OutputConfig.Endpoint == "" {
+			addf("output: bucket and endpoint are required for s3")
+		} else if u, err := url.Parse(c.OutputConfig.Endpoint); err != nil || u.Scheme == "" || u.Host == "" {
+			addf("output: endpoint must be an absolute URL, got %q", c.OutputConfig.Endpoint)
+		}
+		if c.OutputConfig.AccessKeyID == "" || c.OutputConfig.SecretAccessKey == "" {
+			addf("output: access_key_id and secret_access_key are required for s3")
+		}
+	default:
+		addf("output: type must be \"gcs\", \"local\" or \"s3\", got %q", c.OutputConfig.Type)
+	}
+	if c.OutputConfig.BaseURL != "" {
+		if u, err := url.Parse(c.OutputConfig.BaseURL); err != nil || u.Scheme == "" || u.Host == "" {
+			addf("output: base_url must be an absolute URL, got %q", c.OutputConfig.BaseURL)
+		}
+	}
+
+	jobsLoc, err := c.JobsConfig.Location()
+	if err != nil {
+		addf("jobs.timezone: %s", err)
+		jobsLoc = time.UTC
+	}
+	if c.JobsConfig.UpdateActivity != "" {
+		if s, err := ParseSchedule(c.JobsConfig.UpdateActivity, jobsLoc); err != nil {
+			addf("jobs.update_activity: %s", err)
+		} else if s.Next(time.Now()).IsZero() {
+			addf("jobs.update_activity: schedule %q never runs", c.JobsConfig.UpdateActivity)
+		}
+	}
+	if c.JobsConfig.BuildSite != "" {
+		if s, err := ParseSchedule(c.JobsConfig.BuildSite, jobsLoc); err != nil {
+			addf("jobs.build_site: %s", err)
+		} else if s.Next(time.Now()).IsZero() {
+			addf("jobs.build_site: schedule %q never runs", c.JobsConfig.BuildSite)
+		} else if c.OutputConfig.Type == "" {
+			addf("jobs.build_site: an output is required to build the site")
+		}
+	}
+	if c.JobsConfig.Recap != "" {
+		if s, err := ParseSchedule(c.JobsConfig.Recap, jobsLoc); err != nil {
+			addf("jobs.recap: %s", err)
+		} else if s.Next(time.Now()).IsZero() {
+			addf("jobs.recap: schedule %q never runs", c.JobsConfig.Recap)
+		}
+	}
+	if c.JobsConfig.UpdateScores == GameTimeSchedule {
+		// don't call Next, which would fetch the NFL state
+		if _, err := NewGameTimeSchedule(c.JobsConfig.GameTimeConfig); err != nil {
+			addf("jobs.game_time: %s", err)
+		}
+	} else if c.JobsConfig.UpdateScores != "" {
+		if s, err := ParseSchedule(c.JobsConfig.UpdateScores, jobsLoc); err != nil {
+			addf("jobs.update_scores: %s", err)
+		} else if s.Next(time.Now()).IsZero() {
+			addf("jobs.update_scores: schedule %q never runs", c.JobsConfig.UpdateScores)
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// findUnknownKeys returns the paths of any keys in raw that don't map to a
+// json-tagged field of t.  Like encoding/json, keys match field names
+// regardless of case.
+func findUnknownKeys(raw json.RawMessage, t reflect.Type, path string) []string {
+	unknown := make([]string, 0)
+	switch t.Kind() {
+	case reflect.Struct:
+		obj := make(map[string]json.RawMessage)
+		if err := json.Unmarshal(raw, &obj); err != nil {
+			return unknown
+		}
+		fields := make(map[string]reflect.Type)
+		for i := 0; i < t.NumField(); i++ {
+			name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+			if name != "" && name != "-" {
+				fields[strings.ToLower(name)] = t.Field(i).Type
+			}
+		}
+		for k, v := range obj {
+			fieldPath := k
+			if path != "" {
+				fieldPath = path + "." + k
+			}
+			ft, ok := fields[strings.ToLower(k)]
+			if !ok {
+				unknown = append(unknown, fieldPath)
+				continue
+			}
+			unknown = append(unknown, findUnknownKeys(v, ft, fieldPath)...)
+		}
+	case reflect.Slice:
+		arr := make([]json.RawMessage, 0)
+		if err := json.Unmarshal(raw, &arr); err != nil {
+			return unknown
+		}
+		for i, v := range arr {
+			unknown = append(unknown, findUnknownKeys(v, t.Elem(), fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	}
+	return unknown
+}
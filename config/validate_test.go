@@ -0,0 +1,206 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func validConfig() JSON {
+	return JSON{
+		AppID: "app",
+		Token: "token",
+		LeagueConfig: []LeagueConfigJSON{
+			{LeagueType: "sleeper", ID: "123", DiscordCategoryIDs: []string{"category"}},
+		},
+	}
+}
+
+func validationProblems(t *testing.T, err error) []string {
+	t.Helper()
+	if err == nil {
+		return nil
+	}
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("error = %v, want a *ValidationError", err)
+	}
+	return verr.Problems
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(c *JSON)
+		bot    bool
+		want   []string
+	}{
+		{
+			name:   "valid",
+			modify: func(c *JSON) {},
+		},
+		{
+			name: "discord settings aren't required outside the bot",
+			modify: func(c *JSON) {
+				c.AppID = ""
+				c.Token = ""
+			},
+		},
+		{
+			name: "discord settings are required for the bot",
+			modify: func(c *JSON) {
+				c.AppID = ""
+				c.Token = ""
+			},
+			bot:  true,
+			want: []string{"appId is required", "token is required"},
+		},
+		{
+			name: "no leagues",
+			modify: func(c *JSON) {
+				c.LeagueConfig = nil
+			},
+			want: []string{"leagues: at least one league is required"},
+		},
+		{
+			name: "every problem is reported",
+			modify: func(c *JSON) {
+				c.LeagueConfig = append(c.LeagueConfig,
+					LeagueConfigJSON{LeagueType: "yahoo", Name: "other"},
+					LeagueConfigJSON{LeagueType: "sleeper", ID: "123", DiscordCategoryIDs: []string{"category"}, Timezone: "Nowhere/Special"},
+					LeagueConfigJSON{LeagueType: "espn", ID: "456", DiscordCategoryIDs: []string{"espn"}, PowerRankings: map[string]float64{"vibes": 1, "all_play": -1}},
+				)
+				c.StorageConfig.Type = "sqlite"
+				c.OutputConfig.Type = "ftp"
+				c.JobsConfig.Recap = "every tuesday"
+			},
+			want: []string{
+				`leagues[1] (other): type must be "espn" or "sleeper", got "yahoo"`,
+				"leagues[1] (other): id is required",
+				"leagues[1] (other): discord_category_ids is required",
+				"leagues[2]: duplicate sleeper league id 123, also used by leagues[0]",
+				"leagues[2]: timezone: unknown time zone Nowhere/Special",
+				"leagues[2]: discord category category is already mapped to leagues[0]",
+				`leagues[3]: power_rankings: unknown formula "vibes", must be one of all_play, points_for, recent_form`,
+				"leagues[3]: power_rankings: all_play weight must be positive, got -1",
+				"espn_config.year must be between 2018 and ",
+				"storage: path is required for sqlite",
+				`output: type must be "gcs", "local" or "s3", got "ftp"`,
+				"jobs.recap: ",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := validConfig()
+			tt.modify(&c)
+			var err error
+			if tt.bot {
+				err = c.ValidateBot()
+			} else {
+				err = c.Validate()
+			}
+			got := validationProblems(t, err)
+			if len(got) != len(tt.want) {
+				t.Fatalf("problems = %q, want %d problems %q", got, len(tt.want), tt.want)
+			}
+			// map iteration order makes power ranking problems unordered
+			sort.Strings(got)
+			want := append([]string{}, tt.want...)
+			sort.Strings(want)
+			for i := range want {
+				if len(got[i]) < len(want[i]) || got[i][:len(want[i])] != want[i] {
+					t.Errorf("problem %d = %q, want it to start with %q", i, got[i], want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLoadConfigReportsUnknownKeysWithOtherProblems(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	raw := `{
+		"appId": "app",
+		"token": "token",
+		"tokne": "typo",
+		"leagues": [{"type": "sleeper", "id": "123", "discord_category_ids": ["category"], "nmae": "typo"}],
+		"storage": {"type": "sqlite"}
+	}`
+	if err := os.WriteFile(path, []byte(raw), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := LoadConfigFrom(context.Background(), FileSource{Path: path})
+	got := validationProblems(t, err)
+	want := []string{
+		"unknown key leagues[0].nmae",
+		"unknown key tokne",
+		"storage: path is required for sqlite",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("problems = %q, want %q", got, want)
+	}
+}
+
+func TestFindUnknownKeys(t *testing.T) {
+	type inner struct {
+		Name string `json:"name"`
+	}
+	type outer struct {
+		ID       string            `json:"id"`
+		Inner    inner             `json:"inner"`
+		List     []inner           `json:"list"`
+		Map      map[string]string `json:"map"`
+		Ignored  string            `json:"-"`
+		Untagged string
+	}
+	tests := []struct {
+		name string
+		raw  string
+		want []string
+	}{
+		{
+			name: "known keys",
+			raw:  `{"id": "1", "inner": {"name": "x"}, "list": [{"name": "y"}], "map": {"anything": "goes"}}`,
+			want: []string{},
+		},
+		{
+			name: "top level",
+			raw:  `{"id": "1", "idd": "2"}`,
+			want: []string{"idd"},
+		},
+		{
+			name: "nested",
+			raw:  `{"inner": {"nmae": "x"}}`,
+			want: []string{"inner.nmae"},
+		},
+		{
+			name: "in a list",
+			raw:  `{"list": [{"name": "x"}, {"nmae": "y"}]}`,
+			want: []string{"list[1].nmae"},
+		},
+		{
+			name: "any case",
+			raw:  `{"ID": "1", "Inner": {"NAME": "x"}, "lIsT": [{"Name": "y"}]}`,
+			want: []string{},
+		},
+		{
+			name: "untagged and ignored fields",
+			raw:  `{"Ignored": "x", "Untagged": "y", "-": "z"}`,
+			want: []string{"-", "Ignored", "Untagged"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := findUnknownKeys([]byte(tt.raw), reflect.TypeOf(outer{}), "")
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findUnknownKeys(%s) = %q, want %q", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
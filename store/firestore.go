@@ -0,0 +1,442 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/craigatron/football-gobot/config"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// firestoreStore stores each season under its config.LeagueYearKey document,
+// with activity, weeks/<week>/projections, weeks/<week>/results and
+// weeks/<week>/power_rankings subcollections, links under the
+// config.LeagueKey document and server settings in guilds/<guild ID>.
+type firestoreStore struct {
+	client *firestore.Client
+}
+
+// NewFirestore connects to Firestore in the given GCP project.
+func NewFirestore(ctx context.Context, project string) (Store, error) {
+	client, err := firestore.NewClient(ctx, project)
+	if err != nil {
+		return nil, err
+	}
+	return &firestoreStore{client: client}, nil
+}
+
+// activityDoc is an activity document.  Older ESPN documents store player IDs
+// as integers, so Player is decoded loosely.
+type activityDoc struct {
+	Timestamp int64 `firestore:"timestamp"`
+	Actions   []struct {
+		Action   string      `firestore:"Action"`
+		PlayerID interface{} `firestore:"Player"`
+		TeamID   int64       `firestore:"Team"`
+		Pick     string      `firestore:"Pick"`
+		FAAB     int         `firestore:"FAAB"`
+	} `firestore:"actions"`
+}
+
+func (f *firestoreStore) activity(l config.League) *firestore.CollectionRef {
+	return f.client.Collection(fmt.Sprintf("%s/activity", config.LeagueYearKey(l)))
+}
+
+func (f *firestoreStore) projections(l config.League, week int) *firestore.CollectionRef {
+	return f.client.Collection(fmt.Sprintf("%s/weeks/%d/projections", config.LeagueYearKey(l), week))
+}
+
+func (f *firestoreStore) results(l config.League, week int) *firestore.CollectionRef {
+	return f.client.Collection(fmt.Sprintf("%s/weeks/%d/results", config.LeagueYearKey(l), week))
+}
+
+func (f *firestoreStore) powerRankings(l config.League, week int) *firestore.CollectionRef {
+	return f.client.Collection(fmt.Sprintf("%s/weeks/%d/power_rankings", config.LeagueYearKey(l), week))
+}
+
+func (f *firestoreStore) links(l config.League) *firestore.CollectionRef {
+	return f.client.Collection(fmt.Sprintf("%s/links", config.LeagueKey(l)))
+}
+
+// firestoreBatchSize is the most writes Firestore allows in one batch.
+const firestoreBatchSize = 500
+
+// firestoreWrite is a document set as part of a batch.
+type firestoreWrite struct {
+	doc  *firestore.DocumentRef
+	data interface{}
+	opts []firestore.SetOption
+}
+
+// commit sets writes in order, in as many batches as it takes.  A write is
+// only committed once every batch before it has been, so ending with the
+// season document's update marks the rest as saved.
+func (f *firestoreStore) commit(ctx context.Context, writes []firestoreWrite) error {
+	for start := 0; start < len(writes); start += firestoreBatchSize {
+		end := start + firestoreBatchSize
+		if end > len(writes) {
+			end = len(writes)
+		}
+		batch := f.client.Batch()
+		for _, w := range writes[start:end] {
+			batch.Set(w.doc, w.data, w.opts...)
+		}
+		if _, err := batch.Commit(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *firestoreStore) AddActivity(ctx context.Context, l config.League, activity []config.Activity, updated time.Time) error {
+	writes := make([]firestoreWrite, 0, len(activity)+1)
+	for _, a := range activity {
+		writes = append(writes, firestoreWrite{doc: f.activity(l).Doc(a.ID), data: map[string]interface{}{
+			"actions":   a.Actions,
+			"timestamp": a.Timestamp,
+		}})
+	}
+	writes = append(writes, firestoreWrite{
+		doc:  f.client.Doc(config.LeagueYearKey(l)),
+		data: map[string]interface{}{"activity_updated": updated},
+		opts: []firestore.SetOption{firestore.MergeAll},
+	})
+	return f.commit(ctx, writes)
+}
+
+func (f *firestoreStore) RecentActivity(ctx context.Context, l config.League, limit int) ([]config.Activity, error) {
+	return queryActivity(ctx, f.activity(l).OrderBy("timestamp", firestore.Desc).Limit(limit), ActivityQuery{})
+}
+
+func (f *firestoreStore) ActivitySince(ctx context.Context, l config.League, since int64) ([]config.Activity, error) {
+	return queryActivity(ctx, f.activity(l).Where("timestamp", ">", since).OrderBy("timestamp", firestore.Asc), ActivityQuery{})
+}
+
+func (f *firestoreStore) QueryActivity(ctx context.Context, l config.League, q ActivityQuery) ([]config.Activity, error) {
+	dir := firestore.Desc
+	if q.OldestFirst {
+		dir = firestore.Asc
+	}
+	query := f.activity(l).
+		Where("timestamp", ">=", q.Since).
+		Where("timestamp", "<=", q.until()).
+		OrderBy("timestamp", dir).
+		OrderBy(firestore.DocumentID, dir)
+	return queryActivity(ctx, query, q)
+}
+
+// queryActivity reads the activity documents a query returns into a page,
+// and stops reading once it's full.
+func queryActivity(ctx context.Context, q firestore.Query, page ActivityQuery) ([]config.Activity, error) {
+	activity := make([]config.Activity, 0)
+	iter := q.Documents(ctx)
+	defer iter.Stop()
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		ad := activityDoc{}
+		if err := doc.DataTo(&ad); err != nil {
+			return nil, err
+		}
+		a := config.Activity{
+			ID:        doc.Ref.ID,
+			Timestamp: ad.Timestamp,
+			Actions:   make([]config.ActivityAction, 0, len(ad.Actions)),
+		}
+		for _, action := range ad.Actions {
+			a.Actions = append(a.Actions, config.ActivityAction{
+				Action:   action.Action,
+				PlayerID: fmt.Sprint(action.PlayerID),
+				TeamID:   action.TeamID,
+				Pick:     action.Pick,
+				FAAB:     action.FAAB,
+			})
+		}
+		var full bool
+		if activity, full = page.collect(activity, a); full {
+			break
+		}
+	}
+	return activity, nil
+}
+
+func (f *firestoreStore) AddProjections(ctx context.Context, l config.League, week int, projections []Projection, updated time.Time) error {
+	writes := make([]firestoreWrite, 0, len(projections)+1)
+	for _, p := range projections {
+		writes = append(writes, firestoreWrite{doc: f.projections(l, week).Doc(fmt.Sprintf("%d-%d", p.Timestamp, p.TeamID)), data: p})
+	}
+	writes = append(writes, firestoreWrite{
+		doc:  f.client.Doc(config.LeagueYearKey(l)),
+		data: map[string]interface{}{"scores_updated": updated},
+		opts: []firestore.SetOption{firestore.MergeAll},
+	})
+	return f.commit(ctx, writes)
+}
+
+func (f *firestoreStore) Projections(ctx context.Context, l config.League, week int) ([]Projection, error) {
+	projections := make([]Projection, 0)
+	iter := f.projections(l, week).OrderBy("timestamp", firestore.Asc).Documents(ctx)
+	defer iter.Stop()
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		var p Projection
+		if err := doc.DataTo(&p); err != nil {
+			return nil, err
+		}
+		projections = append(projections, p)
+	}
+	return projections, nil
+}
+
+func (f *firestoreStore) CloseWeek(ctx context.Context, l config.League, week int, results []Result) error {
+	writes := make([]firestoreWrite, 0, len(results)+1)
+	for _, r := range results {
+		writes = append(writes, firestoreWrite{doc: f.results(l, week).Doc(fmt.Sprint(r.MatchupID)), data: r})
+	}
+	writes = append(writes, firestoreWrite{
+		doc:  f.client.Doc(config.LeagueYearKey(l)),
+		data: map[string]interface{}{"closed_week": week},
+		opts: []firestore.SetOption{firestore.MergeAll},
+	})
+	return f.commit(ctx, writes)
+}
+
+func (f *firestoreStore) Results(ctx context.Context, l config.League, week int) ([]Result, error) {
+	results := make([]Result, 0)
+	iter := f.results(l, week).OrderBy("matchup_id", firestore.Asc).Documents(ctx)
+	defer iter.Stop()
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		var r Result
+		if err := doc.DataTo(&r); err != nil {
+			return nil, err
+		}
+		results = append(results, r)
+	}
+	return results, nil
+}
+
+func (f *firestoreStore) SetPowerRankings(ctx context.Context, l config.League, week int, rankings []config.PowerRanking) error {
+	writes := make([]firestoreWrite, 0, len(rankings))
+	for _, r := range rankings {
+		writes = append(writes, firestoreWrite{doc: f.powerRankings(l, week).Doc(fmt.Sprint(r.TeamID)), data: r})
+	}
+	return f.commit(ctx, writes)
+}
+
+func (f *firestoreStore) PowerRankings(ctx context.Context, l config.League, week int) ([]config.PowerRanking, error) {
+	rankings := make([]config.PowerRanking, 0)
+	iter := f.powerRankings(l, week).OrderBy("rank", firestore.Asc).Documents(ctx)
+	defer iter.Stop()
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		var r config.PowerRanking
+		if err := doc.DataTo(&r); err != nil {
+			return nil, err
+		}
+		rankings = append(rankings, r)
+	}
+	return rankings, nil
+}
+
+// leagueYearDoc is the season document.  The config field keeps the shape
+// update-activity has always written.
+type leagueYearDoc struct {
+	ActivityUpdated  time.Time `firestore:"activity_updated"`
+	ScoresUpdated    time.Time `firestore:"scores_updated"`
+	ActivityNotified int64     `firestore:"activity_notified"`
+	ClosedWeek       int       `firestore:"closed_week"`
+	ResultsNotified  int       `firestore:"results_notified"`
+	RecapNotified    int       `firestore:"recap_notified"`
+	Config           struct {
+		Members []struct {
+			ID          string `firestore:"id"`
+			DisplayName string `firestore:"displayName"`
+		} `firestore:"members"`
+		Teams []struct {
+			ID           int64    `firestore:"id"`
+			Abbreviation string   `firestore:"abbreviation"`
+			Name         string   `firestore:"name"`
+			Owners       []string `firestore:"owners"`
+		} `firestore:"teams"`
+	} `firestore:"config"`
+}
+
+func (f *firestoreStore) LeagueYear(ctx context.Context, l config.League) (LeagueYear, error) {
+	y := LeagueYear{}
+	doc, err := f.client.Doc(config.LeagueYearKey(l)).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return y, nil
+	}
+	if err != nil {
+		return y, err
+	}
+	d := leagueYearDoc{}
+	if err := doc.DataTo(&d); err != nil {
+		return y, err
+	}
+	y.ActivityUpdated = d.ActivityUpdated
+	y.ScoresUpdated = d.ScoresUpdated
+	y.ActivityNotified = d.ActivityNotified
+	y.ClosedWeek = d.ClosedWeek
+	y.ResultsNotified = d.ResultsNotified
+	y.RecapNotified = d.RecapNotified
+	for _, m := range d.Config.Members {
+		y.Members = append(y.Members, config.Member{ID: m.ID, DisplayName: m.DisplayName})
+	}
+	for _, t := range d.Config.Teams {
+		y.Teams = append(y.Teams, config.Team{ID: t.ID, Name: t.Name, Abbreviation: t.Abbreviation, OwnerIDs: t.Owners})
+	}
+	return y, nil
+}
+
+func (f *firestoreStore) UpdateLeagueYear(ctx context.Context, l config.League, y LeagueYear) error {
+	data := make(map[string]interface{})
+	if !y.ActivityUpdated.IsZero() {
+		data["activity_updated"] = y.ActivityUpdated
+	}
+	if !y.ScoresUpdated.IsZero() {
+		data["scores_updated"] = y.ScoresUpdated
+	}
+	if y.ActivityNotified != 0 {
+		data["activity_notified"] = y.ActivityNotified
+	}
+	if y.ClosedWeek != 0 {
+		data["closed_week"] = y.ClosedWeek
+	}
+	if y.ResultsNotified != 0 {
+		data["results_notified"] = y.ResultsNotified
+	}
+	if y.RecapNotified != 0 {
+		data["recap_notified"] = y.RecapNotified
+	}
+	if y.Members != nil {
+		members := make([]map[string]interface{}, 0, len(y.Members))
+		for _, m := range y.Members {
+			members = append(members, map[string]interface{}{
+				"id":          m.ID,
+				"displayName": m.DisplayName,
+			})
+		}
+		data["config"] = map[string]interface{}{"members": members}
+	}
+	if y.Teams != nil {
+		teams := make([]map[string]interface{}, 0, len(y.Teams))
+		for _, t := range y.Teams {
+			teams = append(teams, map[string]interface{}{
+				"id":           t.ID,
+				"abbreviation": t.Abbreviation,
+				"name":         t.Name,
+				"owners":       t.OwnerIDs,
+			})
+		}
+		c, ok := data["config"].(map[string]interface{})
+		if !ok {
+			c = make(map[string]interface{})
+			data["config"] = c
+		}
+		c["teams"] = teams
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	_, err := f.client.Doc(config.LeagueYearKey(l)).Set(ctx, data, firestore.MergeAll)
+	return err
+}
+
+func (f *firestoreStore) Link(ctx context.Context, l config.League, userID string) (Link, bool, error) {
+	link := Link{}
+	doc, err := f.links(l).Doc(userID).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return link, false, nil
+	}
+	if err != nil {
+		return link, false, err
+	}
+	if err := doc.DataTo(&link); err != nil {
+		return link, false, err
+	}
+	return link, true, nil
+}
+
+func (f *firestoreStore) Links(ctx context.Context, l config.League) (map[string]Link, error) {
+	links := make(map[string]Link)
+	iter := f.links(l).Documents(ctx)
+	defer iter.Stop()
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		link := Link{}
+		if err := doc.DataTo(&link); err != nil {
+			return nil, err
+		}
+		links[doc.Ref.ID] = link
+	}
+	return links, nil
+}
+
+func (f *firestoreStore) SetLink(ctx context.Context, l config.League, userID string, link Link) error {
+	_, err := f.links(l).Doc(userID).Set(ctx, link)
+	return err
+}
+
+// guildDoc is a Discord server's settings document.
+type guildDoc struct {
+	DefaultLeague string `firestore:"default_league"`
+}
+
+func (f *firestoreStore) DefaultLeague(ctx context.Context, guildID string) (string, error) {
+	doc, err := f.client.Collection("guilds").Doc(guildID).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	var g guildDoc
+	if err := doc.DataTo(&g); err != nil {
+		return "", err
+	}
+	return g.DefaultLeague, nil
+}
+
+func (f *firestoreStore) SetDefaultLeague(ctx context.Context, guildID string, l config.League) error {
+	_, err := f.client.Collection("guilds").Doc(guildID).Set(ctx, guildDoc{DefaultLeague: config.LeagueKey(l)})
+	return err
+}
+
+func (f *firestoreStore) Close() error {
+	return f.client.Close()
+}
@@ -0,0 +1,234 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/craigatron/football-gobot/config"
+)
+
+// memoryStore keeps everything in maps keyed by the same paths Firestore
+// uses.  Nothing survives a restart.
+type memoryStore struct {
+	mu          sync.Mutex
+	activity    map[string]map[string]config.Activity
+	projections map[string]map[int][]Projection
+	results     map[string]map[int][]Result
+	rankings    map[string]map[int][]config.PowerRanking
+	leagueYears map[string]LeagueYear
+	links       map[string]map[string]Link
+	guilds      map[string]string
+}
+
+// NewMemory creates an empty in-memory store, for running locally or in tests.
+func NewMemory() Store {
+	return &memoryStore{
+		activity:    make(map[string]map[string]config.Activity),
+		projections: make(map[string]map[int][]Projection),
+		results:     make(map[string]map[int][]Result),
+		rankings:    make(map[string]map[int][]config.PowerRanking),
+		leagueYears: make(map[string]LeagueYear),
+		links:       make(map[string]map[string]Link),
+		guilds:      make(map[string]string),
+	}
+}
+
+func (m *memoryStore) AddActivity(ctx context.Context, l config.League, activity []config.Activity, updated time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := config.LeagueYearKey(l)
+	if _, ok := m.activity[key]; !ok {
+		m.activity[key] = make(map[string]config.Activity)
+	}
+	for _, a := range activity {
+		m.activity[key][a.ID] = a
+	}
+	m.leagueYears[key] = m.leagueYears[key].merge(LeagueYear{ActivityUpdated: updated})
+	return nil
+}
+
+// sortedActivity returns the league's activity oldest first, by timestamp
+// and then ID.
+func (m *memoryStore) sortedActivity(l config.League) []config.Activity {
+	activity := make([]config.Activity, 0)
+	for _, a := range m.activity[config.LeagueYearKey(l)] {
+		activity = append(activity, a)
+	}
+	sort.Slice(activity, func(i, j int) bool {
+		if activity[i].Timestamp != activity[j].Timestamp {
+			return activity[i].Timestamp < activity[j].Timestamp
+		}
+		return activity[i].ID < activity[j].ID
+	})
+	return activity
+}
+
+func (m *memoryStore) RecentActivity(ctx context.Context, l config.League, limit int) ([]config.Activity, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	all := m.sortedActivity(l)
+	activity := make([]config.Activity, 0, limit)
+	for i := len(all) - 1; i >= 0 && len(activity) < limit; i-- {
+		activity = append(activity, all[i])
+	}
+	return activity, nil
+}
+
+func (m *memoryStore) ActivitySince(ctx context.Context, l config.League, since int64) ([]config.Activity, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	activity := make([]config.Activity, 0)
+	for _, a := range m.sortedActivity(l) {
+		if a.Timestamp > since {
+			activity = append(activity, a)
+		}
+	}
+	return activity, nil
+}
+
+func (m *memoryStore) QueryActivity(ctx context.Context, l config.League, q ActivityQuery) ([]config.Activity, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	all := m.sortedActivity(l)
+	page := make([]config.Activity, 0)
+	for i := range all {
+		a := all[i]
+		if !q.OldestFirst {
+			a = all[len(all)-1-i]
+		}
+		if a.Timestamp < q.Since || a.Timestamp > q.until() {
+			continue
+		}
+		var full bool
+		if page, full = q.collect(page, a); full {
+			break
+		}
+	}
+	return page, nil
+}
+
+func (m *memoryStore) AddProjections(ctx context.Context, l config.League, week int, projections []Projection, updated time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := config.LeagueYearKey(l)
+	if _, ok := m.projections[key]; !ok {
+		m.projections[key] = make(map[int][]Projection)
+	}
+	m.projections[key][week] = append(m.projections[key][week], projections...)
+	m.leagueYears[key] = m.leagueYears[key].merge(LeagueYear{ScoresUpdated: updated})
+	return nil
+}
+
+func (m *memoryStore) Projections(ctx context.Context, l config.League, week int) ([]Projection, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	projections := append(make([]Projection, 0), m.projections[config.LeagueYearKey(l)][week]...)
+	sort.SliceStable(projections, func(i, j int) bool {
+		return projections[i].Timestamp < projections[j].Timestamp
+	})
+	return projections, nil
+}
+
+func (m *memoryStore) CloseWeek(ctx context.Context, l config.League, week int, results []Result) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := config.LeagueYearKey(l)
+	if _, ok := m.results[key]; !ok {
+		m.results[key] = make(map[int][]Result)
+	}
+	sorted := append(make([]Result, 0, len(results)), results...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].MatchupID < sorted[j].MatchupID
+	})
+	m.results[key][week] = sorted
+	m.leagueYears[key] = m.leagueYears[key].merge(LeagueYear{ClosedWeek: week})
+	return nil
+}
+
+func (m *memoryStore) Results(ctx context.Context, l config.League, week int) ([]Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append(make([]Result, 0), m.results[config.LeagueYearKey(l)][week]...), nil
+}
+
+func (m *memoryStore) SetPowerRankings(ctx context.Context, l config.League, week int, rankings []config.PowerRanking) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := config.LeagueYearKey(l)
+	if _, ok := m.rankings[key]; !ok {
+		m.rankings[key] = make(map[int][]config.PowerRanking)
+	}
+	sorted := append(make([]config.PowerRanking, 0, len(rankings)), rankings...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Rank < sorted[j].Rank
+	})
+	m.rankings[key][week] = sorted
+	return nil
+}
+
+func (m *memoryStore) PowerRankings(ctx context.Context, l config.League, week int) ([]config.PowerRanking, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append(make([]config.PowerRanking, 0), m.rankings[config.LeagueYearKey(l)][week]...), nil
+}
+
+func (m *memoryStore) LeagueYear(ctx context.Context, l config.League) (LeagueYear, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.leagueYears[config.LeagueYearKey(l)], nil
+}
+
+func (m *memoryStore) UpdateLeagueYear(ctx context.Context, l config.League, y LeagueYear) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := config.LeagueYearKey(l)
+	m.leagueYears[key] = m.leagueYears[key].merge(y)
+	return nil
+}
+
+func (m *memoryStore) Link(ctx context.Context, l config.League, userID string) (Link, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	link, ok := m.links[config.LeagueKey(l)][userID]
+	return link, ok, nil
+}
+
+func (m *memoryStore) Links(ctx context.Context, l config.League) (map[string]Link, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	links := make(map[string]Link)
+	for userID, link := range m.links[config.LeagueKey(l)] {
+		links[userID] = link
+	}
+	return links, nil
+}
+
+func (m *memoryStore) SetLink(ctx context.Context, l config.League, userID string, link Link) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := config.LeagueKey(l)
+	if _, ok := m.links[key]; !ok {
+		m.links[key] = make(map[string]Link)
+	}
+	m.links[key][userID] = link
+	return nil
+}
+
+func (m *memoryStore) DefaultLeague(ctx context.Context, guildID string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.guilds[guildID], nil
+}
+
+func (m *memoryStore) SetDefaultLeague(ctx context.Context, guildID string, l config.League) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.guilds[guildID] = config.LeagueKey(l)
+	return nil
+}
+
+func (m *memoryStore) Close() error {
+	return nil
+}
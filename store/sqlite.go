@@ -0,0 +1,410 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/craigatron/football-gobot/config"
+	// Registers the sqlite3 driver.
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS activity (
+	league_year TEXT NOT NULL,
+	id TEXT NOT NULL,
+	timestamp INTEGER NOT NULL,
+	actions TEXT NOT NULL,
+	PRIMARY KEY (league_year, id)
+);
+CREATE TABLE IF NOT EXISTS projections (
+	league_year TEXT NOT NULL,
+	week INTEGER NOT NULL,
+	timestamp INTEGER NOT NULL,
+	team_id INTEGER NOT NULL,
+	matchup_id INTEGER NOT NULL,
+	projection REAL NOT NULL,
+	win_probability REAL NOT NULL DEFAULT 0,
+	PRIMARY KEY (league_year, week, timestamp, team_id)
+);
+CREATE TABLE IF NOT EXISTS results (
+	league_year TEXT NOT NULL,
+	week INTEGER NOT NULL,
+	matchup_id INTEGER NOT NULL,
+	home_team_id INTEGER NOT NULL,
+	away_team_id INTEGER NOT NULL,
+	home_score REAL NOT NULL,
+	away_score REAL NOT NULL,
+	winner_id INTEGER NOT NULL,
+	PRIMARY KEY (league_year, week, matchup_id)
+);
+CREATE TABLE IF NOT EXISTS power_rankings (
+	league_year TEXT NOT NULL,
+	week INTEGER NOT NULL,
+	team_id INTEGER NOT NULL,
+	rank INTEGER NOT NULL,
+	score REAL NOT NULL,
+	PRIMARY KEY (league_year, week, team_id)
+);
+CREATE TABLE IF NOT EXISTS league_years (
+	league_year TEXT PRIMARY KEY,
+	data TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS links (
+	league TEXT NOT NULL,
+	user_id TEXT NOT NULL,
+	team_id INTEGER NOT NULL,
+	owner_id TEXT NOT NULL,
+	PRIMARY KEY (league, user_id)
+);
+CREATE TABLE IF NOT EXISTS guilds (
+	guild_id TEXT PRIMARY KEY,
+	default_league TEXT NOT NULL
+);
+`
+
+// sqliteColumns are columns added after their table was first created, which
+// are added to existing databases when they're opened.
+var sqliteColumns = []struct {
+	table      string
+	column     string
+	definition string
+}{
+	{"projections", "win_probability", "REAL NOT NULL DEFAULT 0"},
+}
+
+// sqliteStore keys rows by the same paths Firestore uses.  Activity actions
+// and season metadata are stored as JSON.
+type sqliteStore struct {
+	db *sql.DB
+}
+
+// NewSQLite opens the SQLite database at path, creating it if needed.
+func NewSQLite(path string) (Store, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, err
+	}
+	// sqlite only allows one writer at a time anyway
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err := addSQLiteColumns(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &sqliteStore{db: db}, nil
+}
+
+// addSQLiteColumns adds any of sqliteColumns missing from the database.
+func addSQLiteColumns(db *sql.DB) error {
+	for _, c := range sqliteColumns {
+		rows, err := db.Query(fmt.Sprintf("SELECT name FROM pragma_table_info('%s') WHERE name = ?", c.table), c.column)
+		if err != nil {
+			return err
+		}
+		exists := rows.Next()
+		rows.Close()
+		if exists {
+			continue
+		}
+		if _, err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", c.table, c.column, c.definition)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *sqliteStore) AddActivity(ctx context.Context, l config.League, activity []config.Activity, updated time.Time) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, a := range activity {
+		actions, err := json.Marshal(a.Actions)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, "INSERT OR REPLACE INTO activity (league_year, id, timestamp, actions) VALUES (?, ?, ?, ?)",
+			config.LeagueYearKey(l), a.ID, a.Timestamp, string(actions))
+		if err != nil {
+			return err
+		}
+	}
+	if err := updateLeagueYear(ctx, tx, l, LeagueYear{ActivityUpdated: updated}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *sqliteStore) RecentActivity(ctx context.Context, l config.League, limit int) ([]config.Activity, error) {
+	return s.queryActivity(ctx, ActivityQuery{}, "SELECT id, timestamp, actions FROM activity WHERE league_year = ? ORDER BY timestamp DESC LIMIT ?",
+		config.LeagueYearKey(l), limit)
+}
+
+func (s *sqliteStore) ActivitySince(ctx context.Context, l config.League, since int64) ([]config.Activity, error) {
+	return s.queryActivity(ctx, ActivityQuery{}, "SELECT id, timestamp, actions FROM activity WHERE league_year = ? AND timestamp > ? ORDER BY timestamp ASC",
+		config.LeagueYearKey(l), since)
+}
+
+func (s *sqliteStore) QueryActivity(ctx context.Context, l config.League, q ActivityQuery) ([]config.Activity, error) {
+	order := "DESC"
+	if q.OldestFirst {
+		order = "ASC"
+	}
+	query := fmt.Sprintf("SELECT id, timestamp, actions FROM activity WHERE league_year = ? AND timestamp >= ? AND timestamp <= ? ORDER BY timestamp %s, id %s", order, order)
+	return s.queryActivity(ctx, q, query, config.LeagueYearKey(l), q.Since, q.until())
+}
+
+// queryActivity reads the activity rows a query returns into a page, and
+// stops reading once it's full.
+func (s *sqliteStore) queryActivity(ctx context.Context, page ActivityQuery, query string, args ...interface{}) ([]config.Activity, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	activity := make([]config.Activity, 0)
+	for rows.Next() {
+		var a config.Activity
+		var actions string
+		if err := rows.Scan(&a.ID, &a.Timestamp, &actions); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(actions), &a.Actions); err != nil {
+			return nil, err
+		}
+		var full bool
+		if activity, full = page.collect(activity, a); full {
+			break
+		}
+	}
+	return activity, rows.Err()
+}
+
+func (s *sqliteStore) AddProjections(ctx context.Context, l config.League, week int, projections []Projection, updated time.Time) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, p := range projections {
+		_, err := tx.ExecContext(ctx, "INSERT OR REPLACE INTO projections (league_year, week, timestamp, team_id, matchup_id, projection, win_probability) VALUES (?, ?, ?, ?, ?, ?, ?)",
+			config.LeagueYearKey(l), week, p.Timestamp, p.TeamID, p.MatchupID, p.Projection, p.WinProbability)
+		if err != nil {
+			return err
+		}
+	}
+	if err := updateLeagueYear(ctx, tx, l, LeagueYear{ScoresUpdated: updated}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *sqliteStore) Projections(ctx context.Context, l config.League, week int) ([]Projection, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT timestamp, team_id, matchup_id, projection, win_probability FROM projections WHERE league_year = ? AND week = ? ORDER BY timestamp ASC",
+		config.LeagueYearKey(l), week)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	projections := make([]Projection, 0)
+	for rows.Next() {
+		var p Projection
+		if err := rows.Scan(&p.Timestamp, &p.TeamID, &p.MatchupID, &p.Projection, &p.WinProbability); err != nil {
+			return nil, err
+		}
+		projections = append(projections, p)
+	}
+	return projections, rows.Err()
+}
+
+func (s *sqliteStore) CloseWeek(ctx context.Context, l config.League, week int, results []Result) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, r := range results {
+		_, err := tx.ExecContext(ctx, "INSERT OR REPLACE INTO results (league_year, week, matchup_id, home_team_id, away_team_id, home_score, away_score, winner_id) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+			config.LeagueYearKey(l), week, r.MatchupID, r.HomeTeamID, r.AwayTeamID, r.HomeScore, r.AwayScore, r.WinnerID)
+		if err != nil {
+			return err
+		}
+	}
+	if err := updateLeagueYear(ctx, tx, l, LeagueYear{ClosedWeek: week}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *sqliteStore) Results(ctx context.Context, l config.League, week int) ([]Result, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT matchup_id, home_team_id, away_team_id, home_score, away_score, winner_id FROM results WHERE league_year = ? AND week = ? ORDER BY matchup_id ASC",
+		config.LeagueYearKey(l), week)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := make([]Result, 0)
+	for rows.Next() {
+		var r Result
+		if err := rows.Scan(&r.MatchupID, &r.HomeTeamID, &r.AwayTeamID, &r.HomeScore, &r.AwayScore, &r.WinnerID); err != nil {
+			return nil, err
+		}
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
+
+func (s *sqliteStore) SetPowerRankings(ctx context.Context, l config.League, week int, rankings []config.PowerRanking) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, "DELETE FROM power_rankings WHERE league_year = ? AND week = ?", config.LeagueYearKey(l), week); err != nil {
+		return err
+	}
+	for _, r := range rankings {
+		_, err := tx.ExecContext(ctx, "INSERT INTO power_rankings (league_year, week, team_id, rank, score) VALUES (?, ?, ?, ?, ?)",
+			config.LeagueYearKey(l), week, r.TeamID, r.Rank, r.Score)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *sqliteStore) PowerRankings(ctx context.Context, l config.League, week int) ([]config.PowerRanking, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT team_id, rank, score FROM power_rankings WHERE league_year = ? AND week = ? ORDER BY rank ASC",
+		config.LeagueYearKey(l), week)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rankings := make([]config.PowerRanking, 0)
+	for rows.Next() {
+		var r config.PowerRanking
+		if err := rows.Scan(&r.TeamID, &r.Rank, &r.Score); err != nil {
+			return nil, err
+		}
+		rankings = append(rankings, r)
+	}
+	return rankings, rows.Err()
+}
+
+// queryer is the part of sql.DB and sql.Tx used to read and write seasons.
+type queryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+func getLeagueYear(ctx context.Context, q queryer, l config.League) (LeagueYear, error) {
+	y := LeagueYear{}
+	var data string
+	err := q.QueryRowContext(ctx, "SELECT data FROM league_years WHERE league_year = ?", config.LeagueYearKey(l)).Scan(&data)
+	if err == sql.ErrNoRows {
+		return y, nil
+	}
+	if err != nil {
+		return y, err
+	}
+	err = json.Unmarshal([]byte(data), &y)
+	return y, err
+}
+
+func updateLeagueYear(ctx context.Context, q queryer, l config.League, update LeagueYear) error {
+	y, err := getLeagueYear(ctx, q, l)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(y.merge(update))
+	if err != nil {
+		return err
+	}
+	_, err = q.ExecContext(ctx, "INSERT OR REPLACE INTO league_years (league_year, data) VALUES (?, ?)", config.LeagueYearKey(l), string(data))
+	return err
+}
+
+func (s *sqliteStore) LeagueYear(ctx context.Context, l config.League) (LeagueYear, error) {
+	return getLeagueYear(ctx, s.db, l)
+}
+
+func (s *sqliteStore) UpdateLeagueYear(ctx context.Context, l config.League, y LeagueYear) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := updateLeagueYear(ctx, tx, l, y); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *sqliteStore) Link(ctx context.Context, l config.League, userID string) (Link, bool, error) {
+	link := Link{}
+	err := s.db.QueryRowContext(ctx, "SELECT team_id, owner_id FROM links WHERE league = ? AND user_id = ?",
+		config.LeagueKey(l), userID).Scan(&link.TeamID, &link.OwnerID)
+	if err == sql.ErrNoRows {
+		return link, false, nil
+	}
+	if err != nil {
+		return link, false, err
+	}
+	return link, true, nil
+}
+
+func (s *sqliteStore) Links(ctx context.Context, l config.League) (map[string]Link, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT user_id, team_id, owner_id FROM links WHERE league = ?", config.LeagueKey(l))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	links := make(map[string]Link)
+	for rows.Next() {
+		var userID string
+		link := Link{}
+		if err := rows.Scan(&userID, &link.TeamID, &link.OwnerID); err != nil {
+			return nil, err
+		}
+		links[userID] = link
+	}
+	return links, rows.Err()
+}
+
+func (s *sqliteStore) SetLink(ctx context.Context, l config.League, userID string, link Link) error {
+	_, err := s.db.ExecContext(ctx, "INSERT OR REPLACE INTO links (league, user_id, team_id, owner_id) VALUES (?, ?, ?, ?)",
+		config.LeagueKey(l), userID, link.TeamID, link.OwnerID)
+	return err
+}
+
+func (s *sqliteStore) DefaultLeague(ctx context.Context, guildID string) (string, error) {
+	var league string
+	err := s.db.QueryRowContext(ctx, "SELECT default_league FROM guilds WHERE guild_id = ?", guildID).Scan(&league)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return league, err
+}
+
+func (s *sqliteStore) SetDefaultLeague(ctx context.Context, guildID string, l config.League) error {
+	_, err := s.db.ExecContext(ctx, "INSERT OR REPLACE INTO guilds (guild_id, default_league) VALUES (?, ?)",
+		guildID, config.LeagueKey(l))
+	return err
+}
+
+func (s *sqliteStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,220 @@
+// Package store persists league data shared by the bot and the update jobs.
+package store
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"time"
+
+	"github.com/craigatron/football-gobot/config"
+)
+
+// Store saves league activity, projections, season metadata, Discord links
+// and Discord server settings.  Activity, projections and metadata are per
+// season; links are per league.
+type Store interface {
+	// AddActivity saves league activity and sets the season's
+	// ActivityUpdated to updated once all of it is saved.
+	AddActivity(ctx context.Context, l config.League, activity []config.Activity, updated time.Time) error
+	// RecentActivity returns the newest saved activity, newest first.
+	RecentActivity(ctx context.Context, l config.League, limit int) ([]config.Activity, error)
+	// ActivitySince returns activity newer than the given timestamp, oldest
+	// first.
+	ActivitySince(ctx context.Context, l config.League, since int64) ([]config.Activity, error)
+	// QueryActivity returns a page of the activity matching q, reading only
+	// as much as it needs.
+	QueryActivity(ctx context.Context, l config.League, q ActivityQuery) ([]config.Activity, error)
+
+	// AddProjections saves projections for a week and sets the season's
+	// ScoresUpdated to updated once all of them are saved.
+	AddProjections(ctx context.Context, l config.League, week int, projections []Projection, updated time.Time) error
+	// Projections returns every projection saved for a week, oldest first.
+	Projections(ctx context.Context, l config.League, week int) ([]Projection, error)
+
+	// CloseWeek saves a week's final results and sets the season's
+	// ClosedWeek to week once all of them are saved.
+	CloseWeek(ctx context.Context, l config.League, week int, results []Result) error
+	// Results returns the final results saved for a week by matchup ID,
+	// which are empty until the week is closed.
+	Results(ctx context.Context, l config.League, week int) ([]Result, error)
+
+	// SetPowerRankings saves a week's power rankings, replacing any saved
+	// before.
+	SetPowerRankings(ctx context.Context, l config.League, week int, rankings []config.PowerRanking) error
+	// PowerRankings returns the power rankings saved for a week, best first,
+	// which are empty until they're set.
+	PowerRankings(ctx context.Context, l config.League, week int) ([]config.PowerRanking, error)
+
+	// LeagueYear returns metadata for the league's current season.  A season
+	// with nothing saved yet returns the zero value.
+	LeagueYear(ctx context.Context, l config.League) (LeagueYear, error)
+	// UpdateLeagueYear sets the non-zero fields of y, leaving the rest as is.
+	UpdateLeagueYear(ctx context.Context, l config.League, y LeagueYear) error
+
+	// Link returns the team linked to a Discord user, if any.
+	Link(ctx context.Context, l config.League, userID string) (Link, bool, error)
+	// Links returns every link in the league keyed by Discord user ID.
+	Links(ctx context.Context, l config.League) (map[string]Link, error)
+	SetLink(ctx context.Context, l config.League, userID string, link Link) error
+
+	// DefaultLeague returns the config.LeagueKey of a Discord server's
+	// default league, or "" if it doesn't have one.
+	DefaultLeague(ctx context.Context, guildID string) (string, error)
+	SetDefaultLeague(ctx context.Context, guildID string, l config.League) error
+
+	Close() error
+}
+
+// Projection is a team's projected score at a point in time.
+type Projection struct {
+	MatchupID  int64   `firestore:"matchup_id"`
+	Projection float64 `firestore:"projection"`
+	TeamID     int64   `firestore:"team_id"`
+	// Timestamp is when the projection was fetched, in Unix milliseconds.
+	Timestamp int64 `firestore:"timestamp"`
+	// WinProbability is the team's chance of winning the matchup as of
+	// Timestamp, from 0 to 1.  Projections saved before it was tracked have
+	// zero for both teams.
+	WinProbability float64 `firestore:"win_probability"`
+}
+
+// Result is a matchup's final score.
+type Result struct {
+	MatchupID  int64   `firestore:"matchup_id"`
+	HomeTeamID int64   `firestore:"home_team_id"`
+	AwayTeamID int64   `firestore:"away_team_id"`
+	HomeScore  float64 `firestore:"home_score"`
+	AwayScore  float64 `firestore:"away_score"`
+	// WinnerID is the winning team's ID, or zero for a tie.
+	WinnerID int64 `firestore:"winner_id"`
+}
+
+// NewResult records a finished matchup's score and winner.
+func NewResult(m config.Matchup) Result {
+	r := Result{
+		MatchupID:  m.ID,
+		HomeTeamID: m.HomeTeamID,
+		AwayTeamID: m.AwayTeamID,
+		HomeScore:  m.HomeScore,
+		AwayScore:  m.AwayScore,
+	}
+	if m.HomeScore > m.AwayScore {
+		r.WinnerID = m.HomeTeamID
+	} else if m.AwayScore > m.HomeScore {
+		r.WinnerID = m.AwayTeamID
+	}
+	return r
+}
+
+// Link links a Discord user to a fantasy team.
+type Link struct {
+	TeamID  int64  `firestore:"team_id"`
+	OwnerID string `firestore:"owner_id"`
+}
+
+// ActivityQuery selects a page of a league's activity, ordered by timestamp
+// and then ID.
+type ActivityQuery struct {
+	// Since and Until bound the activity's timestamps in Unix milliseconds,
+	// inclusive.  An Until of 0 leaves them unbounded.
+	Since int64
+	Until int64
+	// Match, if set, skips activity it returns false for.
+	Match func(config.Activity) bool
+	// OldestFirst returns the oldest activity first, instead of the newest.
+	OldestFirst bool
+	// Limit is how much activity to return, or 0 for all of it.  Activity
+	// with the same timestamp as the last returned is included past the
+	// limit, so the next page can start from the next timestamp.
+	Limit int
+}
+
+// until is the upper bound of the query's timestamps.
+func (q ActivityQuery) until() int64 {
+	if q.Until == 0 {
+		return math.MaxInt64
+	}
+	return q.Until
+}
+
+// collect adds a to page if it matches, reporting whether the page is full
+// once a has been considered.  Activity must be passed in query order.
+func (q ActivityQuery) collect(page []config.Activity, a config.Activity) ([]config.Activity, bool) {
+	if q.Limit > 0 && len(page) >= q.Limit && a.Timestamp != page[len(page)-1].Timestamp {
+		return page, true
+	}
+	if q.Match == nil || q.Match(a) {
+		page = append(page, a)
+	}
+	return page, false
+}
+
+// LeagueYear is metadata about a league's season.
+type LeagueYear struct {
+	ActivityUpdated time.Time
+	ScoresUpdated   time.Time
+	// ActivityNotified is the timestamp of the newest activity posted to the
+	// league's update channels, or zero if none has been.
+	ActivityNotified int64
+	// ClosedWeek is the last week whose final results have been saved.
+	// Weeks are closed in order, so every week before it is closed too.
+	ClosedWeek int
+	// ResultsNotified is the last closed week whose results have been posted
+	// to the league's update channels.
+	ResultsNotified int
+	// RecapNotified is the last closed week whose recap has been posted to
+	// the league's update channels.
+	RecapNotified int
+	// Teams and Members are saved the first time the season's activity is
+	// fetched, so old activity can be shown after teams change.
+	Teams   []config.Team
+	Members []config.Member
+}
+
+// merge returns y with the non-zero fields of update set.
+func (y LeagueYear) merge(update LeagueYear) LeagueYear {
+	if !update.ActivityUpdated.IsZero() {
+		y.ActivityUpdated = update.ActivityUpdated
+	}
+	if !update.ScoresUpdated.IsZero() {
+		y.ScoresUpdated = update.ScoresUpdated
+	}
+	if update.ActivityNotified != 0 {
+		y.ActivityNotified = update.ActivityNotified
+	}
+	if update.ClosedWeek != 0 {
+		y.ClosedWeek = update.ClosedWeek
+	}
+	if update.ResultsNotified != 0 {
+		y.ResultsNotified = update.ResultsNotified
+	}
+	if update.RecapNotified != 0 {
+		y.RecapNotified = update.RecapNotified
+	}
+	if update.Teams != nil {
+		y.Teams = update.Teams
+	}
+	if update.Members != nil {
+		y.Members = update.Members
+	}
+	return y
+}
+
+// New opens the store selected by the config.
+func New(ctx context.Context, c config.StorageConfigJSON) (Store, error) {
+	switch c.Type {
+	case "", "firestore":
+		project := c.Project
+		if project == "" {
+			project = os.Getenv("PROJECT")
+		}
+		return NewFirestore(ctx, project)
+	case "sqlite":
+		return NewSQLite(c.Path)
+	case "memory":
+		return NewMemory(), nil
+	}
+	return nil, fmt.Errorf("unknown storage type %s", c.Type)
+}
@@ -0,0 +1,415 @@
+package store
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/craigatron/football-gobot/config"
+	"github.com/craigatron/football-gobot/config/configtest"
+)
+
+var (
+	league      = configtest.League{}
+	lastSeason  = configtest.League{LeagueSeason: "2021"}
+	otherLeague = configtest.League{LeagueID: "2"}
+)
+
+// stores are the Stores that can run without any outside services.
+var stores = map[string]func(t *testing.T) Store{
+	"memory": func(t *testing.T) Store {
+		return NewMemory()
+	},
+	"sqlite": func(t *testing.T) Store {
+		s, err := NewSQLite(":memory:")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	},
+}
+
+// TestStores runs the same tests against every Store, so they all behave the
+// same.
+func TestStores(t *testing.T) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, s Store)
+	}{
+		{"activity", testActivity},
+		{"query activity", testQueryActivity},
+		{"projections", testProjections},
+		{"results", testResults},
+		{"power rankings", testPowerRankings},
+		{"league year", testLeagueYear},
+		{"links", testLinks},
+		{"default league", testDefaultLeague},
+	}
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					s := newStore(t)
+					defer s.Close()
+					tt.test(t, s)
+				})
+			}
+		})
+	}
+}
+
+func activity(id string, timestamp int64) config.Activity {
+	return config.Activity{
+		ID:        id,
+		Timestamp: timestamp,
+		Actions: []config.ActivityAction{
+			{Action: config.ActionWaiverAdded, PlayerID: "p" + id, TeamID: 1, FAAB: 3},
+			{Action: config.ActionDropped, PlayerID: "d" + id, TeamID: 1},
+		},
+	}
+}
+
+func activityIDs(activity []config.Activity) []string {
+	ids := make([]string, 0, len(activity))
+	for _, a := range activity {
+		ids = append(ids, a.ID)
+	}
+	return ids
+}
+
+func testActivity(t *testing.T, s Store) {
+	ctx := context.Background()
+	updated := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+	if err := s.AddActivity(ctx, league, []config.Activity{activity("b", 200), activity("a", 100), activity("c", 300)}, updated); err != nil {
+		t.Fatal(err)
+	}
+	// re-adding activity replaces it rather than duplicating it
+	if err := s.AddActivity(ctx, league, []config.Activity{activity("c", 300)}, updated); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddActivity(ctx, otherLeague, []config.Activity{activity("x", 150)}, updated); err != nil {
+		t.Fatal(err)
+	}
+
+	recent, err := s.RecentActivity(ctx, league, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := activityIDs(recent), []string{"c", "b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("RecentActivity() = %q, want %q", got, want)
+	}
+	if !reflect.DeepEqual(recent[0], activity("c", 300)) {
+		t.Errorf("RecentActivity()[0] = %+v, want %+v", recent[0], activity("c", 300))
+	}
+
+	since, err := s.ActivitySince(ctx, league, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := activityIDs(since), []string{"b", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ActivitySince(100) = %q, want %q", got, want)
+	}
+
+	y, err := s.LeagueYear(ctx, league)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !y.ActivityUpdated.Equal(updated) {
+		t.Errorf("ActivityUpdated = %s, want %s", y.ActivityUpdated, updated)
+	}
+
+	previous, err := s.RecentActivity(ctx, lastSeason, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(previous) != 0 {
+		t.Errorf("RecentActivity() for last season = %q, want none", activityIDs(previous))
+	}
+}
+
+func testQueryActivity(t *testing.T, s Store) {
+	ctx := context.Background()
+	all := []config.Activity{
+		activity("a", 100),
+		activity("b", 200),
+		// c and d share a timestamp, so they're ordered by ID
+		activity("d", 300),
+		activity("c", 300),
+		activity("e", 400),
+		activity("f", 500),
+	}
+	if err := s.AddActivity(ctx, league, all, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddActivity(ctx, otherLeague, []config.Activity{activity("x", 250)}, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		q    ActivityQuery
+		want []string
+	}{
+		{"everything", ActivityQuery{}, []string{"f", "e", "d", "c", "b", "a"}},
+		{"oldest first", ActivityQuery{OldestFirst: true}, []string{"a", "b", "c", "d", "e", "f"}},
+		{"bounded", ActivityQuery{Since: 200, Until: 400}, []string{"e", "d", "c", "b"}},
+		{"limited", ActivityQuery{Limit: 2}, []string{"f", "e"}},
+		{"limit includes the last timestamp", ActivityQuery{Limit: 3}, []string{"f", "e", "d", "c"}},
+		{"oldest first limit includes the last timestamp", ActivityQuery{OldestFirst: true, Limit: 3}, []string{"a", "b", "c", "d"}},
+		{"next page", ActivityQuery{Until: 299, Limit: 3}, []string{"b", "a"}},
+		{
+			name: "matched",
+			q: ActivityQuery{Limit: 2, Match: func(a config.Activity) bool {
+				return a.ID != "f" && a.ID != "d"
+			}},
+			want: []string{"e", "c"},
+		},
+		{"nothing", ActivityQuery{Since: 600}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.QueryActivity(ctx, league, tt.q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ids := activityIDs(got); !reflect.DeepEqual(ids, tt.want) {
+				t.Errorf("QueryActivity(%+v) = %q, want %q", tt.q, ids, tt.want)
+			}
+		})
+	}
+}
+
+func testProjections(t *testing.T, s Store) {
+	ctx := context.Background()
+	updated := time.Date(2022, 10, 2, 13, 0, 0, 0, time.UTC)
+	later := []Projection{
+		{MatchupID: 1, TeamID: 1, Projection: 110, Timestamp: 2000, WinProbability: 0.6},
+		{MatchupID: 1, TeamID: 2, Projection: 100, Timestamp: 2000, WinProbability: 0.4},
+	}
+	earlier := []Projection{
+		{MatchupID: 1, TeamID: 1, Projection: 105, Timestamp: 1000, WinProbability: 0.5},
+		{MatchupID: 1, TeamID: 2, Projection: 105, Timestamp: 1000, WinProbability: 0.5},
+	}
+	if err := s.AddProjections(ctx, league, 3, later, updated); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddProjections(ctx, league, 3, earlier, updated); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddProjections(ctx, league, 4, earlier, updated); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := s.Projections(ctx, league, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 4 {
+		t.Fatalf("Projections(3) = %+v, want 4 projections", got)
+	}
+	for i := 1; i < len(got); i++ {
+		if got[i].Timestamp < got[i-1].Timestamp {
+			t.Errorf("Projections(3) = %+v, want oldest first", got)
+		}
+	}
+	if got[3].WinProbability != 0.6 && got[2].WinProbability != 0.6 {
+		t.Errorf("Projections(3) = %+v, want win probabilities saved", got)
+	}
+
+	y, err := s.LeagueYear(ctx, league)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !y.ScoresUpdated.Equal(updated) {
+		t.Errorf("ScoresUpdated = %s, want %s", y.ScoresUpdated, updated)
+	}
+}
+
+func testResults(t *testing.T, s Store) {
+	ctx := context.Background()
+	results := []Result{
+		NewResult(config.Matchup{ID: 2, HomeTeamID: 3, AwayTeamID: 4, HomeScore: 90, AwayScore: 90}),
+		NewResult(config.Matchup{ID: 1, HomeTeamID: 1, AwayTeamID: 2, HomeScore: 100, AwayScore: 120.5}),
+	}
+	if results[0].WinnerID != 0 || results[1].WinnerID != 2 {
+		t.Fatalf("NewResult() winners = %d and %d, want a tie and 2", results[0].WinnerID, results[1].WinnerID)
+	}
+
+	none, err := s.Results(ctx, league, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(none) != 0 {
+		t.Errorf("Results(1) before closing = %+v, want none", none)
+	}
+
+	if err := s.CloseWeek(ctx, league, 1, results); err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.Results(ctx, league, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Result{results[1], results[0]}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Results(1) = %+v, want %+v", got, want)
+	}
+
+	y, err := s.LeagueYear(ctx, league)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if y.ClosedWeek != 1 {
+		t.Errorf("ClosedWeek = %d, want 1", y.ClosedWeek)
+	}
+}
+
+func testPowerRankings(t *testing.T, s Store) {
+	ctx := context.Background()
+	first := []config.PowerRanking{
+		{TeamID: 2, Rank: 2, Score: 0.5},
+		{TeamID: 1, Rank: 1, Score: 0.75},
+		{TeamID: 3, Rank: 3, Score: 0.25},
+	}
+	if err := s.SetPowerRankings(ctx, league, 1, first); err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.PowerRankings(ctx, league, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []config.PowerRanking{first[1], first[0], first[2]}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("PowerRankings(1) = %+v, want %+v", got, want)
+	}
+
+	replaced := []config.PowerRanking{
+		{TeamID: 1, Rank: 2, Score: 0.5},
+		{TeamID: 2, Rank: 1, Score: 0.75},
+		{TeamID: 3, Rank: 3, Score: 0.25},
+	}
+	if err := s.SetPowerRankings(ctx, league, 1, replaced); err != nil {
+		t.Fatal(err)
+	}
+	got, err = s.PowerRankings(ctx, league, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []config.PowerRanking{replaced[1], replaced[0], replaced[2]}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("PowerRankings(1) after replacing = %+v, want %+v", got, want)
+	}
+
+	none, err := s.PowerRankings(ctx, league, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(none) != 0 {
+		t.Errorf("PowerRankings(2) = %+v, want none", none)
+	}
+}
+
+func testLeagueYear(t *testing.T, s Store) {
+	ctx := context.Background()
+	y, err := s.LeagueYear(ctx, league)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(y, LeagueYear{}) {
+		t.Errorf("LeagueYear() before saving = %+v, want the zero value", y)
+	}
+
+	teams := []config.Team{{ID: 1, Name: "Team One", Abbreviation: "ONE", OwnerIDs: []string{"owner"}}}
+	members := []config.Member{{ID: "owner", DisplayName: "Owner"}}
+	if err := s.UpdateLeagueYear(ctx, league, LeagueYear{ActivityNotified: 100, Teams: teams, Members: members}); err != nil {
+		t.Fatal(err)
+	}
+	// zero fields leave what's saved as is
+	if err := s.UpdateLeagueYear(ctx, league, LeagueYear{ResultsNotified: 2, RecapNotified: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	y, err = s.LeagueYear(ctx, league)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := LeagueYear{ActivityNotified: 100, ResultsNotified: 2, RecapNotified: 1, Teams: teams, Members: members}
+	if !reflect.DeepEqual(y, want) {
+		t.Errorf("LeagueYear() = %+v, want %+v", y, want)
+	}
+
+	other, err := s.LeagueYear(ctx, lastSeason)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(other, LeagueYear{}) {
+		t.Errorf("LeagueYear() for last season = %+v, want the zero value", other)
+	}
+}
+
+func testLinks(t *testing.T, s Store) {
+	ctx := context.Background()
+	if _, ok, err := s.Link(ctx, league, "user"); err != nil || ok {
+		t.Fatalf("Link() before linking = %t, %v, want not found", ok, err)
+	}
+
+	if err := s.SetLink(ctx, league, "user", Link{TeamID: 1, OwnerID: "owner"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetLink(ctx, league, "user", Link{TeamID: 2, OwnerID: "other"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetLink(ctx, league, "friend", Link{TeamID: 3}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetLink(ctx, otherLeague, "user", Link{TeamID: 4}); err != nil {
+		t.Fatal(err)
+	}
+
+	link, ok, err := s.Link(ctx, league, "user")
+	if err != nil || !ok {
+		t.Fatalf("Link() = %t, %v, want found", ok, err)
+	}
+	if want := (Link{TeamID: 2, OwnerID: "other"}); link != want {
+		t.Errorf("Link() = %+v, want %+v", link, want)
+	}
+
+	// links outlast seasons
+	links, err := s.Links(ctx, lastSeason)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]Link{
+		"user":   {TeamID: 2, OwnerID: "other"},
+		"friend": {TeamID: 3},
+	}
+	if !reflect.DeepEqual(links, want) {
+		t.Errorf("Links() = %+v, want %+v", links, want)
+	}
+}
+
+func testDefaultLeague(t *testing.T, s Store) {
+	ctx := context.Background()
+	key, err := s.DefaultLeague(ctx, "guild")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key != "" {
+		t.Errorf("DefaultLeague() before setting = %q, want none", key)
+	}
+
+	if err := s.SetDefaultLeague(ctx, "guild", league); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetDefaultLeague(ctx, "guild", otherLeague); err != nil {
+		t.Fatal(err)
+	}
+	key, err = s.DefaultLeague(ctx, "guild")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := config.LeagueKey(otherLeague); key != want {
+		t.Errorf("DefaultLeague() = %q, want %q", key, want)
+	}
+}